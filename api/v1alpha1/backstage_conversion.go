@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+)
+
+// ConversionDataAnnotation keeps the v1alpha2 spec fields which can not be represented in v1alpha1,
+// so they are not lost when the object is updated through v1alpha1 and converted back to v1alpha2.
+// The status is not kept, as it is a subresource not changed by the updates of the object.
+const ConversionDataAnnotation = "rhdh.redhat.com/conversion-data"
+
+// ConvertTo converts this Backstage to the Hub version (v1alpha2)
+func (src *Backstage) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha2.Backstage)
+
+	// v1alpha1 is a subset of v1alpha2 with the same JSON representation
+	srcContent, err := toContent(src)
+	if err != nil {
+		return err
+	}
+
+	if data, ok := src.Annotations[ConversionDataAnnotation]; ok {
+		lost := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(data), &lost); err != nil {
+			return fmt.Errorf("failed to read %s annotation: %w", ConversionDataAnnotation, err)
+		}
+		// the status kept by the former versions is outdated
+		delete(lost, "status")
+		restoreLost(lost, srcContent)
+	}
+
+	if err := fromContent(srcContent, dst); err != nil {
+		return err
+	}
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	delete(dst.Annotations, ConversionDataAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha2) to this version
+func (dst *Backstage) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha2.Backstage)
+
+	srcContent, err := toContent(src)
+	if err != nil {
+		return err
+	}
+	if err := fromContent(srcContent, dst); err != nil {
+		return err
+	}
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dstContent, err := toContent(dst)
+	if err != nil {
+		return err
+	}
+	lost := findLost(map[string]interface{}{"spec": srcContent["spec"]}, map[string]interface{}{"spec": dstContent["spec"]})
+	if len(lost) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(lost)
+	if err != nil {
+		return fmt.Errorf("failed to write %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(data)
+	return nil
+}
+
+// toContent returns spec and status of the object as a generic JSON map
+func toContent(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Backstage: %w", err)
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Backstage: %w", err)
+	}
+	return map[string]interface{}{"spec": content["spec"], "status": content["status"]}, nil
+}
+
+// fromContent sets spec and status of the object from a generic JSON map, ignoring unknown fields
+func fromContent(content map[string]interface{}, obj interface{}) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Backstage: %w", err)
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to unmarshal Backstage: %w", err)
+	}
+	return nil
+}
+
+// findLost returns the fields of src missing in dst.
+// Arrays are compared element by element if they are of the same length.
+func findLost(src, dst map[string]interface{}) map[string]interface{} {
+	lost := map[string]interface{}{}
+	for key, srcValue := range src {
+		dstValue, ok := dst[key]
+		if !ok {
+			if srcValue != nil {
+				lost[key] = srcValue
+			}
+			continue
+		}
+		if l := findLostValue(srcValue, dstValue); l != nil {
+			lost[key] = l
+		}
+	}
+	return lost
+}
+
+func findLostValue(srcValue, dstValue interface{}) interface{} {
+	switch s := srcValue.(type) {
+	case map[string]interface{}:
+		if d, ok := dstValue.(map[string]interface{}); ok {
+			if l := findLost(s, d); len(l) > 0 {
+				return l
+			}
+		}
+	case []interface{}:
+		if d, ok := dstValue.([]interface{}); ok && len(s) == len(d) {
+			lost := make([]interface{}, len(s))
+			found := false
+			for i := range s {
+				if lost[i] = findLostValue(s[i], d[i]); lost[i] != nil {
+					found = true
+				}
+			}
+			if found {
+				return lost
+			}
+		}
+	}
+	return nil
+}
+
+// restoreLost adds the lost fields to dst unless dst already has them.
+// Arrays are restored element by element if they are of the same length.
+func restoreLost(lost, dst map[string]interface{}) {
+	for key, lostValue := range lost {
+		dstValue, ok := dst[key]
+		if !ok || dstValue == nil {
+			dst[key] = lostValue
+			continue
+		}
+		restoreLostValue(lostValue, dstValue)
+	}
+}
+
+func restoreLostValue(lostValue, dstValue interface{}) {
+	switch l := lostValue.(type) {
+	case map[string]interface{}:
+		if d, ok := dstValue.(map[string]interface{}); ok {
+			restoreLost(l, d)
+		}
+	case []interface{}:
+		if d, ok := dstValue.([]interface{}); ok && len(l) == len(d) {
+			for i := range l {
+				if l[i] != nil {
+					restoreLostValue(l[i], d[i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func hubBackstage() *v1alpha2.Backstage {
+	return &v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bs1",
+			Namespace:   "ns1",
+			Labels:      map[string]string{"label": "value"},
+			Annotations: map[string]string{"annotation": "value"},
+		},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				AppConfig: &v1alpha2.AppConfig{
+					MountPath:  "/my/path",
+					ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "cm1"}, {Name: "cm2", Key: "key2"}},
+				},
+				DynamicPluginsConfigMapName: "dp",
+				ExtraEnvs: &v1alpha2.ExtraEnvs{
+					Envs: []v1alpha2.Env{{Name: "ENV1", Value: "val1"}},
+				},
+				Replicas: ptr.To(int32(2)),
+				Route: &v1alpha2.Route{
+					Enabled: ptr.To(true),
+					Host:    "backstage.example.com",
+				},
+				// v1alpha2 only
+				Ingress: &v1alpha2.Ingress{
+					Host:      "backstage.example.com",
+					ClassName: ptr.To("nginx"),
+				},
+			},
+			Database: &v1alpha2.Database{
+				EnableLocalDb: ptr.To(false),
+			},
+			// v1alpha2 only
+			Deployment: &v1alpha2.BackstageDeployment{
+				Patch: &apiextensionsv1.JSON{Raw: []byte(`{"spec":{"replicas":3}}`)},
+			},
+		},
+		Status: v1alpha2.BackstageStatus{
+			Conditions: []metav1.Condition{{Type: "Deployed", Status: metav1.ConditionTrue, Reason: "Deployed"}},
+			// v1alpha2 only
+			ObservedGeneration: 3,
+			URL:                "https://backstage.example.com",
+		},
+	}
+}
+
+func TestConvertHubRoundTrip(t *testing.T) {
+	hub := hubBackstage()
+
+	spoke := &Backstage{}
+	assert.NoError(t, spoke.ConvertFrom(hub))
+
+	assert.Equal(t, hub.Name, spoke.Name)
+	assert.Equal(t, "value", spoke.Labels["label"])
+	assert.Equal(t, "value", spoke.Annotations["annotation"])
+	assert.Equal(t, "/my/path", spoke.Spec.Application.AppConfig.MountPath)
+	assert.Equal(t, "key2", spoke.Spec.Application.AppConfig.ConfigMaps[1].Key)
+	assert.Equal(t, "backstage.example.com", spoke.Spec.Application.Route.Host)
+	assert.Equal(t, int32(2), *spoke.Spec.Application.Replicas)
+	assert.False(t, *spoke.Spec.Database.EnableLocalDb)
+	assert.Equal(t, hub.Status.Conditions, spoke.Status.Conditions)
+	assert.Contains(t, spoke.Annotations, ConversionDataAnnotation)
+	// v1alpha2 only status fields are not kept
+	assert.NotContains(t, spoke.Annotations[ConversionDataAnnotation], "status")
+	assert.NotContains(t, spoke.Annotations[ConversionDataAnnotation], "https://backstage.example.com")
+
+	restored := &v1alpha2.Backstage{}
+	assert.NoError(t, spoke.ConvertTo(restored))
+	assert.Equal(t, hub.ObjectMeta, restored.ObjectMeta)
+	assert.Equal(t, hub.Spec, restored.Spec)
+	assert.Equal(t, hub.Status.Conditions, restored.Status.Conditions)
+	assert.Empty(t, restored.Status.URL)
+}
+
+func TestConvertIgnoresKeptStatus(t *testing.T) {
+	// the annotation written by the former versions
+	spoke := &Backstage{ObjectMeta: metav1.ObjectMeta{
+		Name:        "bs1",
+		Annotations: map[string]string{ConversionDataAnnotation: `{"status":{"url":"https://old.example.com"}}`},
+	}}
+
+	hub := &v1alpha2.Backstage{}
+	assert.NoError(t, spoke.ConvertTo(hub))
+	assert.Empty(t, hub.Status.URL)
+}
+
+func TestConvertSpokeRoundTrip(t *testing.T) {
+	spoke := &Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bs1",
+			Namespace: "ns1",
+		},
+		Spec: BackstageSpec{
+			Application: &Application{
+				ExtraFiles: &ExtraFiles{
+					MountPath: "/files",
+					Secrets:   []ObjectKeyRef{{Name: "s1", Key: "k1"}},
+				},
+				Image:            ptr.To("quay.io/my/backstage:1"),
+				ImagePullSecrets: []string{"pull-secret"},
+			},
+			RawRuntimeConfig: &RuntimeConfig{BackstageConfigName: "raw"},
+		},
+		Status: BackstageStatus{
+			Conditions: []metav1.Condition{{Type: "Deployed", Status: metav1.ConditionFalse, Reason: "DeployFailed"}},
+		},
+	}
+
+	hub := &v1alpha2.Backstage{}
+	assert.NoError(t, spoke.ConvertTo(hub))
+	assert.Equal(t, "/files", hub.Spec.Application.ExtraFiles.MountPath)
+	assert.Equal(t, "quay.io/my/backstage:1", *hub.Spec.Application.Image)
+	assert.Equal(t, "raw", hub.Spec.RawRuntimeConfig.BackstageConfigName)
+	assert.Nil(t, hub.Annotations)
+
+	restored := &Backstage{}
+	assert.NoError(t, restored.ConvertFrom(hub))
+	assert.NotContains(t, restored.Annotations, ConversionDataAnnotation)
+	assert.Equal(t, spoke, restored)
+}
+
+func TestConvertKeepsSpokeChanges(t *testing.T) {
+	spoke := &Backstage{}
+	assert.NoError(t, spoke.ConvertFrom(hubBackstage()))
+
+	// updated through v1alpha1
+	spoke.Spec.Application.Route.Host = "new.example.com"
+	spoke.Spec.Application.AppConfig = nil
+
+	hub := &v1alpha2.Backstage{}
+	assert.NoError(t, spoke.ConvertTo(hub))
+	assert.Equal(t, "new.example.com", hub.Spec.Application.Route.Host)
+	assert.Nil(t, hub.Spec.Application.AppConfig)
+	// v1alpha2 only fields are preserved
+	assert.Equal(t, "nginx", *hub.Spec.Application.Ingress.ClassName)
+	assert.JSONEq(t, `{"spec":{"replicas":3}}`, string(hub.Spec.Deployment.Patch.Raw))
+	assert.NotContains(t, hub.Annotations, ConversionDataAnnotation)
+}
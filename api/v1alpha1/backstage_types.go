@@ -97,9 +97,9 @@ type Application struct {
 	ExtraEnvs *ExtraEnvs `json:"extraEnvs,omitempty"`
 
 	// Number of desired replicas to set in the Backstage Deployment.
-	// Defaults to 1.
+	// If not set here nor in the default or raw configuration, the Operator does not manage the replicas (the Deployment defaults them to 1),
+	// so they can be scaled by others, e.g. HorizontalPodAutoscaler.
 	// +optional
-	//+kubebuilder:default=1
 	Replicas *int32 `json:"replicas,omitempty"`
 
 	// Custom image to use in all containers (including Init Containers).
@@ -15,7 +15,9 @@
 package v1alpha2
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 )
@@ -25,13 +27,34 @@ type BackstageConditionReason string
 type BackstageConditionType string
 
 const (
-	BackstageConditionTypeDeployed BackstageConditionType = "Deployed"
+	BackstageConditionTypeDeployed    BackstageConditionType = "Deployed"
+	BackstageConditionTypeReady       BackstageConditionType = "Ready"
+	BackstageConditionTypeProgressing BackstageConditionType = "Progressing"
+	BackstageConditionTypeDegraded    BackstageConditionType = "Degraded"
+	// outcome of the dynamic plugins installation by the install-dynamic-plugins init container
+	BackstageConditionTypePluginsInstalled BackstageConditionType = "PluginsInstalled"
 
 	BackstageConditionReasonDeployed   BackstageConditionReason = "Deployed"
 	BackstageConditionReasonFailed     BackstageConditionReason = "DeployFailed"
 	BackstageConditionReasonInProgress BackstageConditionReason = "DeployInProgress"
+	// some runtime objects could not be applied as the fields are managed by other field managers
+	BackstageConditionReasonApplyConflict BackstageConditionReason = "ApplyConflict"
+
+	BackstageConditionReasonRolloutComplete     BackstageConditionReason = "RolloutComplete"
+	BackstageConditionReasonRolloutInProgress   BackstageConditionReason = "RolloutInProgress"
+	BackstageConditionReasonAsExpected          BackstageConditionReason = "AsExpected"
+	BackstageConditionReasonInitContainerFailed BackstageConditionReason = "InitContainerFailed"
+	BackstageConditionReasonWorkloadNotFound    BackstageConditionReason = "WorkloadNotFound"
+
+	BackstageConditionReasonPluginsInstalled    BackstageConditionReason = "InstallSucceeded"
+	BackstageConditionReasonPluginInstallFailed BackstageConditionReason = "InstallFailed"
+	BackstageConditionReasonPluginsInstalling   BackstageConditionReason = "InstallInProgress"
 )
 
+// PlanOnlyAnnotation set to "true" on Backstage CR makes the Operator calculate the changes of the runtime objects
+// and publish them in the status (see BackstageStatus.Plan) instead of applying them
+const PlanOnlyAnnotation = "rhdh.redhat.com/plan-only"
+
 // BackstageSpec defines the desired state of Backstage
 type BackstageSpec struct {
 	// Configuration for Backstage. Optional.
@@ -89,9 +112,8 @@ type Application struct {
 	// and will be mounted inside the main application container under a specified mount directory.
 	// Additionally, each file will be passed as a `--config /mount/path/to/configmap/key` to the
 	// main container args in the order of the entries defined in the AppConfigs list.
-	// But bear in mind that for a single ConfigMap element containing several filenames,
-	// the order in which those files will be appended to the main container args cannot be guaranteed.
-	// So if you want to pass multiple app-config files, it is recommended to pass one ConfigMap per app-config file.
+	// The files of a single ConfigMap element are appended in the order defined by AppConfig.FileOrder,
+	// the ones not listed there sorted by name.
 	// +optional
 	AppConfig *AppConfig `json:"appConfig,omitempty"`
 
@@ -101,6 +123,24 @@ type Application struct {
 	// +optional
 	DynamicPluginsConfigMapName string `json:"dynamicPluginsConfigMapName,omitempty"`
 
+	// Dynamic plugins defined right in the Backstage CR. They are merged by package into the dynamic plugins
+	// configuration (the default one and the one of DynamicPluginsConfigMapName, if set),
+	// overriding the fields specified there.
+	// +optional
+	// +listType=map
+	// +listMapKey=package
+	DynamicPlugins []DynamicPlugin `json:"dynamicPlugins,omitempty"`
+
+	// Persistent cache of the installed dynamic plugins. If set, the plugins are installed into a PersistentVolumeClaim
+	// provisioned by the Operator instead of the ephemeral dynamic-plugins-root volume, in a directory keyed by the hash
+	// of the effective dynamic plugins configuration, so the pods starting with unchanged plugins skip the installation.
+	// +optional
+	DynamicPluginsCache *DynamicPluginsCache `json:"dynamicPluginsCache,omitempty"`
+
+	// Credentials and certificates the dynamic plugins are downloaded from the registries with.
+	// +optional
+	DynamicPluginsRegistry *DynamicPluginsRegistry `json:"dynamicPluginsRegistry,omitempty"`
+
 	// References to existing Config objects to use as extra config files.
 	// They will be mounted as files in the specified mount path.
 	// Each element can be a reference to any ConfigMap or Secret.
@@ -112,9 +152,9 @@ type Application struct {
 	ExtraEnvs *ExtraEnvs `json:"extraEnvs,omitempty"`
 
 	// Number of desired replicas to set in the Backstage Deployment.
-	// Defaults to 1.
+	// If not set here nor in the default or raw configuration, the Operator does not manage the replicas (the Deployment defaults them to 1),
+	// so they can be scaled by others, e.g. HorizontalPodAutoscaler.
 	// +optional
-	//+kubebuilder:default=1
 	Replicas *int32 `json:"replicas,omitempty"`
 
 	// Custom image to use in all containers (including Init Containers).
@@ -128,6 +168,22 @@ type Application struct {
 
 	// Route configuration. Used for OpenShift only.
 	Route *Route `json:"route,omitempty"`
+
+	// Ingress configuration. Used for non-OpenShift clusters only.
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+
+	// Gateway API HTTPRoute configuration.
+	// Used only if Gateway API (gateway.networking.k8s.io) is available on the cluster.
+	// +optional
+	HTTPRoute *HTTPRoute `json:"httpRoute,omitempty"`
+
+	// Whether changes of the referenced ConfigMaps and Secrets (app-configs, extra files, extra envs, dynamic plugins)
+	// restart the Backstage Pods right away. If false, the changes are rolled out on the next deployment,
+	// i.e. when the Backstage CR changes. Can be overridden per reference with restartOnChange.
+	// Defaults to true, unless set otherwise by the deprecated EXT_CONF_SYNC_backstage env variable of the Operator.
+	// +optional
+	RestartOnConfigChange *bool `json:"restartOnConfigChange,omitempty"`
 }
 
 type AppConfig struct {
@@ -144,6 +200,80 @@ type AppConfig struct {
 	// More details on https://backstage.io/docs/conf/writing/.
 	// +optional
 	ConfigMaps []ObjectKeyRef `json:"configMaps,omitempty"`
+
+	// Order of the app-config files (ConfigMap keys) within each of the ConfigMaps, which matters as the later files
+	// override the values of the former ones. The files listed here go first, in this order,
+	// the others follow sorted by name. The ConfigMaps themselves are passed in the order of the ConfigMaps field.
+	// +optional
+	FileOrder []string `json:"fileOrder,omitempty"`
+
+	// If true, each ConfigMap is mounted as a directory (MountPath/<ConfigMap name>) instead of a file per key,
+	// so the changes made to the ConfigMaps are propagated to the running Backstage, which reloads its configuration
+	// without restarting the Pods. Changes of these ConfigMaps do not restart the Pods then,
+	// while changes of extra files, environment variables and dynamic plugins still do.
+	// +optional
+	HotReload bool `json:"hotReload,omitempty"`
+
+	// App-config content defined right in the Backstage CR, as an alternative to a separate ConfigMap.
+	// The Operator generates a ConfigMap from it, mounted under the MountPath as the other app-config files.
+	// It is passed to Backstage after the ConfigMaps, so it overrides the values defined there.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Inline *apiextensionsv1.JSON `json:"inline,omitempty"`
+}
+
+// DynamicPlugin is an entry of the dynamic plugins configuration (dynamic-plugins.yaml)
+type DynamicPlugin struct {
+	// Package of the plugin: a path in the dynamic plugins root, NPM package or OCI image reference.
+	// +kubebuilder:validation:MinLength=1
+	Package string `json:"package"`
+
+	// Integrity checksum of the package, required for the packages downloaded from a registry.
+	// +optional
+	Integrity string `json:"integrity,omitempty"`
+
+	// Whether the plugin is disabled.
+	// +optional
+	Disabled *bool `json:"disabled,omitempty"`
+
+	// Configuration of the plugin, added to the app-config generated by the install-dynamic-plugins init container.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PluginConfig *apiextensionsv1.JSON `json:"pluginConfig,omitempty"`
+}
+
+// DynamicPluginsCache configures the PersistentVolumeClaim caching the installed dynamic plugins
+type DynamicPluginsCache struct {
+	// Size of the cache. Defaults to the size of the default configuration, or 5Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// StorageClass of the cache. The cluster default StorageClass is used if not set.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Access modes of the cache. Defaults to ReadWriteOnce, which allows the pods of a single node only:
+	// with more than one replica (or a rolling update), the pods on other nodes fail to start unless ReadWriteMany is set.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
+// DynamicPluginsRegistry refers the objects the install-dynamic-plugins init container uses to access the plugin registries
+type DynamicPluginsRegistry struct {
+	// Secret with the npm configuration of the registries of the NPM packaged plugins, such as the registry URLs and auth tokens.
+	// The key is .npmrc, unless specified. Replaces the dynamic-plugins-npmrc Secret of the default configuration.
+	// +optional
+	Npmrc *ObjectKeyRef `json:"npmrc,omitempty"`
+
+	// ConfigMap with the bundle of additional CA certificates (PEM) of the registries, trusted along with the system ones.
+	// The key is ca-bundle.crt, unless specified.
+	// +optional
+	CABundle *ObjectKeyRef `json:"caBundle,omitempty"`
+
+	// Secret with the credentials of the registries of the OCI packaged plugins, in the format of ~/.docker/config.json
+	// (e.g. kubernetes.io/dockerconfigjson type). The key is .dockerconfigjson, unless specified.
+	// +optional
+	OCIAuth *ObjectKeyRef `json:"ociAuth,omitempty"`
 }
 
 type ExtraFiles struct {
@@ -191,6 +321,17 @@ type ObjectKeyRef struct {
 	// Key in the object
 	// +optional
 	Key string `json:"key,omitempty"`
+
+	// Namespace of the object, if other than the namespace of the Backstage CR.
+	// Such an object has to be allowed for the namespace of the Backstage CR by a BackstageReferenceGrant in the object namespace.
+	// The operator mirrors the object into the namespace of the Backstage CR.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Whether changes of the object restart the Backstage Pods right away.
+	// Overrides spec.application.restartOnConfigChange for this object.
+	// +optional
+	RestartOnChange *bool `json:"restartOnChange,omitempty"`
 }
 
 type Env struct {
@@ -208,11 +349,111 @@ type BackstageStatus struct {
 	// Conditions is the list of conditions describing the state of the runtime
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The generation of Backstage CR most recently reconciled by the Operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Status of the Backstage Deployment
+	// +optional
+	Backstage *ComponentStatus `json:"backstage,omitempty"`
+
+	// Status of the local database StatefulSet. Not set if local database is disabled.
+	// +optional
+	LocalDb *ComponentStatus `json:"localDb,omitempty"`
+
+	// URL Backstage is exposed at, resolved from the host admitted by the Route on OpenShift,
+	// the Gateway API HTTPRoute accepted by the Gateway
+	// or from the Ingress host (or its load balancer address) on other clusters.
+	// Not set until the host is admitted or if Backstage is not exposed.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Runtime objects which differ from the desired state configured by the Operator.
+	// Reported only if the Operator does not own the runtime objects and runs in drift report mode,
+	// in which case the existing runtime objects are neither updated nor deleted.
+	// +optional
+	Drift []ObjectDrift `json:"drift,omitempty"`
+
+	// Changes of the runtime objects pending while the Backstage CR is annotated with rhdh.redhat.com/plan-only
+	// +optional
+	Plan *Plan `json:"plan,omitempty"`
+}
+
+// Plan is the list of changes the Operator would make to the runtime objects
+type Plan struct {
+	// The generation of Backstage CR the plan is calculated for
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// Runtime objects to be created
+	// +optional
+	Create []RuntimeObjectRef `json:"create,omitempty"`
+
+	// Runtime objects to be updated, with the fields to be changed
+	// +optional
+	Update []ObjectDrift `json:"update,omitempty"`
+
+	// Runtime objects to be deleted
+	// +optional
+	Delete []RuntimeObjectRef `json:"delete,omitempty"`
+
+	// Whether the Backstage Pods would be restarted, i.e. the Deployment's Pod template changes
+	// (including the external configuration hash annotation)
+	PodRestart bool `json:"podRestart"`
+}
+
+// RuntimeObjectRef refers to a runtime object
+type RuntimeObjectRef struct {
+	// Kind of the runtime object
+	Kind string `json:"kind"`
+
+	// Name of the runtime object
+	Name string `json:"name"`
+}
+
+// ObjectDrift describes how a runtime object differs from its desired state
+type ObjectDrift struct {
+	// Kind of the runtime object
+	Kind string `json:"kind"`
+
+	// Name of the runtime object
+	Name string `json:"name"`
+
+	// Paths of the fields which differ from the desired state, e.g. spec.template.spec.containers[0].image
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+
+	// True if the runtime object is not desired anymore (e.g. disabled in the spec) and would be deleted
+	// +optional
+	Delete bool `json:"delete,omitempty"`
+}
+
+// ComponentStatus is the observed state of a Backstage runtime workload (Deployment or StatefulSet)
+type ComponentStatus struct {
+	// Name of the workload object
+	Name string `json:"name"`
+
+	// Number of desired replicas
+	Replicas int32 `json:"replicas"`
+
+	// Number of available replicas (ready for at least minReadySeconds)
+	AvailableReplicas int32 `json:"availableReplicas"`
+
+	// Reason of the workload's pods failure, if any.
+	// For example: ImagePullBackOff, CrashLoopBackOff, InitContainerFailed
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Human-readable details of the failure
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Backstage is the Schema for the backstages API
 type Backstage struct {
@@ -265,6 +506,77 @@ type Route struct {
 	TLS *TLS `json:"tls,omitempty"`
 }
 
+// Ingress specifies configuration parameters for Kubernetes Ingress for Backstage.
+type Ingress struct {
+	// Control the creation of an Ingress on non-OpenShift clusters.
+	// Note that an Ingress is created only if either it is defined in the default/raw configuration (ingress.yaml)
+	// or this field (spec.application.ingress) is defined.
+	// +optional
+	//+kubebuilder:default=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Host is a fully qualified domain name Backstage is exposed at. Optional.
+	// Ignored if Enabled is false.
+	// If not specified, the Ingress accepts requests for any host.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9]))*$`
+	Host string `json:"host,omitempty"`
+
+	// Name of the IngressClass cluster resource, which defines the Ingress controller implementing this Ingress.
+	// If not specified, the cluster default IngressClass is used.
+	// Ignored if Enabled is false.
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+
+	// Name of the Secret containing TLS certificate and key (tls.crt and tls.key) for the Host.
+	// If specified, TLS is terminated on the Ingress.
+	// Ignored if Enabled is false.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// Annotations to add to the Ingress, typically to configure the Ingress controller.
+	// Ignored if Enabled is false.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HTTPRoute specifies configuration parameters for Gateway API HTTPRoute for Backstage.
+type HTTPRoute struct {
+	// Control the creation of an HTTPRoute.
+	// Note that an HTTPRoute is created only if either it is defined in the default/raw configuration (httproute.yaml)
+	// or this field (spec.application.httpRoute) is defined.
+	// +optional
+	//+kubebuilder:default=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Gateway the HTTPRoute attaches to.
+	// Ignored if Enabled is false.
+	// +optional
+	ParentRef *GatewayRef `json:"parentRef,omitempty"`
+
+	// Hostnames to match against the HTTP Host header. Optional.
+	// If not specified, the hostnames of the Gateway listener are used.
+	// Ignored if Enabled is false.
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+// GatewayRef is a reference to Gateway API Gateway
+type GatewayRef struct {
+	// Name of the Gateway
+	//+kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the Gateway. Defaults to the namespace of Backstage.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the Gateway listener to attach to. If not specified, attaches to all the listeners allowing it.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
 type TLS struct {
 	// certificate provides certificate contents. This should be a single serving certificate, not a certificate
 	// chain. Do not include a CA certificate.
@@ -307,6 +619,109 @@ func (s *BackstageSpec) IsRouteEnabled() bool {
 	return true
 }
 
+// IsIngressEnabled returns value of Application.Ingress.Enabled if defined or true by default
+func (s *BackstageSpec) IsIngressEnabled() bool {
+	if s.Application != nil && s.Application.Ingress != nil {
+		return ptr.Deref(s.Application.Ingress.Enabled, true)
+	}
+	return true
+}
+
+// IsHTTPRouteEnabled returns value of Application.HTTPRoute.Enabled if defined or true by default
+func (s *BackstageSpec) IsHTTPRouteEnabled() bool {
+	if s.Application != nil && s.Application.HTTPRoute != nil {
+		return ptr.Deref(s.Application.HTTPRoute.Enabled, true)
+	}
+	return true
+}
+
+// ExternalConfigRefs returns the references to the external ConfigMaps or Secrets (kind) of the Backstage instance,
+// including the raw runtime config, the dynamic plugins ConfigMap and the dynamic plugins registry objects
+func (s *BackstageSpec) ExternalConfigRefs(kind string) []ObjectKeyRef {
+	var refs []ObjectKeyRef
+	if kind == "ConfigMap" && s.RawRuntimeConfig != nil {
+		for _, name := range []string{s.RawRuntimeConfig.BackstageConfigName, s.RawRuntimeConfig.LocalDbConfigName} {
+			if name != "" {
+				refs = append(refs, ObjectKeyRef{Name: name})
+			}
+		}
+	}
+	app := s.Application
+	if app == nil {
+		return refs
+	}
+	switch kind {
+	case "ConfigMap":
+		if app.AppConfig != nil {
+			refs = append(refs, app.AppConfig.ConfigMaps...)
+		}
+		if app.ExtraFiles != nil {
+			refs = append(refs, app.ExtraFiles.ConfigMaps...)
+		}
+		if app.ExtraEnvs != nil {
+			refs = append(refs, app.ExtraEnvs.ConfigMaps...)
+		}
+		if app.DynamicPluginsConfigMapName != "" {
+			refs = append(refs, ObjectKeyRef{Name: app.DynamicPluginsConfigMapName})
+		}
+		if app.DynamicPluginsRegistry != nil && app.DynamicPluginsRegistry.CABundle != nil {
+			refs = append(refs, *app.DynamicPluginsRegistry.CABundle)
+		}
+	case "Secret":
+		if app.ExtraFiles != nil {
+			refs = append(refs, app.ExtraFiles.Secrets...)
+		}
+		if app.ExtraEnvs != nil {
+			refs = append(refs, app.ExtraEnvs.Secrets...)
+		}
+		if reg := app.DynamicPluginsRegistry; reg != nil {
+			for _, ref := range []*ObjectKeyRef{reg.Npmrc, reg.OCIAuth} {
+				if ref != nil {
+					refs = append(refs, *ref)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// RefNamespace returns the namespace of the object referred by ref
+func (b *Backstage) RefNamespace(ref ObjectKeyRef) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return b.Namespace
+}
+
+// IsRestartOnChange returns true if changes of the ConfigMap or Secret (kind) with the namespace and name
+// restart the Backstage Pods right away, according to the restartOnChange of the references to the object
+// or to Application.RestartOnConfigChange (byDefault if not set)
+func (b *Backstage) IsRestartOnChange(kind, namespace, name string, byDefault bool) bool {
+	if b.Spec.Application != nil {
+		byDefault = ptr.Deref(b.Spec.Application.RestartOnConfigChange, byDefault)
+	}
+	for _, ref := range b.Spec.ExternalConfigRefs(kind) {
+		if ref.Name == name && b.RefNamespace(ref) == namespace && ptr.Deref(ref.RestartOnChange, byDefault) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHotReloaded returns true if the ConfigMap or Secret (kind) with the namespace and name is an app-config
+// hot reloaded by Backstage (see AppConfig.HotReload), so its changes do not restart the Backstage Pods
+func (b *Backstage) IsHotReloaded(kind, namespace, name string) bool {
+	if kind != "ConfigMap" || b.Spec.Application == nil || b.Spec.Application.AppConfig == nil || !b.Spec.Application.AppConfig.HotReload {
+		return false
+	}
+	for _, ref := range b.Spec.Application.AppConfig.ConfigMaps {
+		if ref.Name == name && b.RefNamespace(ref) == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *BackstageSpec) IsAuthSecretSpecified() bool {
 	return s.Database != nil && s.Database.AuthSecretName != ""
 }
@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackstageReferenceGrantSpec defines the Backstage CR namespaces allowed to refer the ConfigMaps and Secrets
+// of the grant's namespace
+type BackstageReferenceGrantSpec struct {
+	// Namespaces of the Backstage CRs allowed to refer the objects
+	//+kubebuilder:validation:MinItems=1
+	From []ReferenceGrantFrom `json:"from"`
+
+	// Objects which can be referred
+	//+kubebuilder:validation:MinItems=1
+	To []ReferenceGrantTo `json:"to"`
+}
+
+type ReferenceGrantFrom struct {
+	// Namespace of the Backstage CRs
+	//+kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+}
+
+type ReferenceGrantTo struct {
+	// Kind of the object
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Name of the object. If not specified, all the objects of the kind can be referred.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BackstageReferenceGrant allows Backstage CRs of other namespaces to refer ConfigMaps and Secrets
+// of its namespace (see ObjectKeyRef.Namespace)
+type BackstageReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BackstageReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BackstageReferenceGrantList contains a list of BackstageReferenceGrant
+type BackstageReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BackstageReferenceGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BackstageReferenceGrant{}, &BackstageReferenceGrantList{})
+}
+
+// Allows returns true if the grant allows Backstage CRs of the namespace to refer the object of the kind with the name
+func (g *BackstageReferenceGrant) Allows(namespace, kind, name string) bool {
+	from := false
+	for _, f := range g.Spec.From {
+		if f.Namespace == namespace {
+			from = true
+			break
+		}
+	}
+	if !from {
+		return false
+	}
+	for _, t := range g.Spec.To {
+		if t.Kind == kind && (t.Name == "" || t.Name == name) {
+			return true
+		}
+	}
+	return false
+}
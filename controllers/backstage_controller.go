@@ -17,7 +17,16 @@ package controller
 import (
 	"context"
 	"fmt"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -25,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -39,7 +49,10 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 
+	networkingv1 "k8s.io/api/networking/v1"
+
 	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
 	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 
@@ -50,33 +63,49 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var watchedConfigSelector = metav1.LabelSelector{
-	MatchExpressions: []metav1.LabelSelectorRequirement{
-		{
-			Key:      model.ExtConfigSyncLabel,
-			Values:   []string{"true"},
-			Operator: metav1.LabelSelectorOpIn,
-		},
-	},
-}
+// how often not yet ready Backstage runtime is re-checked
+const notReadyRequeueInterval = 30 * time.Second
+
+// FieldManager is the field manager the Operator applies runtime objects with
+const FieldManager = "backstage-operator"
+
+// field managers of client-side updates made by the Operator before it switched to server-side apply
+// (not set explicitly, so derived from the binary name)
+var csaFieldManagers = sets.New("manager")
 
 // BackstageReconciler reconciles a Backstage object
 type BackstageReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	// reads the objects not worth caching (e.g. Pods) directly from the API server,
+	// the Client is used if not set
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
 	// If true, Backstage Controller always sync the state of runtime objects created
 	// otherwise, runtime objects can be re-configured independently
 	OwnsRuntime bool
-	// indicates if current cluster is Openshift
-	IsOpenShift bool
+	// capabilities of current cluster (Openshift, Gateway API)
+	Platform model.Platform
+	// If true and the Controller does not own runtime objects, existing runtime objects are not updated,
+	// but their drift from the desired state is reported in the Backstage status and Events
+	ReportDrift bool
+	// records Events related to Backstage objects
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=rhdh.redhat.com,resources=backstages,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rhdh.redhat.com,resources=backstages/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=rhdh.redhat.com,resources=backstages/finalizers,verbs=update
+//+kubebuilder:rbac:groups=rhdh.redhat.com,resources=backstagereferencegrants,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps;secrets;services,verbs=get;watch;create;update;list;delete;patch
-//+kubebuilder:rbac:groups="",resources=persistentvolumes;persistentvolumeclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;watch;create;update;list;delete;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups="apps",resources=deployments;statefulsets,verbs=get;watch;create;update;list;delete;patch
+//+kubebuilder:rbac:groups="apps",resources=replicasets,verbs=get;list
+//+kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;watch;create;update;list;delete;patch
+//+kubebuilder:rbac:groups="gateway.networking.k8s.io",resources=httproutes,verbs=get;watch;create;update;list;delete;patch
+//+kubebuilder:rbac:groups="gateway.networking.k8s.io",resources=gateways,verbs=get;list;watch
 //+kubebuilder:rbac:groups="route.openshift.io",resources=routes;routes/custom-host,verbs=get;watch;create;update;list;delete;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -90,6 +119,7 @@ func (r *BackstageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err := r.Get(ctx, req.NamespacedName, &backstage); err != nil {
 		if errors.IsNotFound(err) {
 			lg.Info("backstage gone from the namespace")
+			removeInstanceMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, fmt.Errorf("failed to load backstage deployment from the cluster: %w", err)
@@ -112,175 +142,451 @@ func (r *BackstageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// 1. Preliminary read and prepare external config objects from the specs (configMaps, Secrets)
 	// 2. Make some validation to fail fast
+	start := time.Now()
 	externalConfig, err := r.preprocessSpec(ctx, backstage)
+	observePhase(phasePreprocess, start)
 	if err != nil {
-		return ctrl.Result{}, errorAndStatus(&backstage, "failed to preprocess backstage spec", err)
+		// e.g. a BackstageReferenceGrant is revoked, the mirrors of the objects it allowed must not stay
+		if err := r.cleanUngrantedMirrors(ctx, &backstage); err != nil {
+			lg.Error(err, "failed to clean mirrors of the objects not granted anymore")
+		}
+		return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonPreprocessFailed, "failed to preprocess backstage spec", err)
 	}
 
 	// This creates array of model objects to be reconsiled
-	bsModel, err := model.InitObjects(ctx, backstage, externalConfig, r.OwnsRuntime, r.IsOpenShift, r.Scheme)
+	start = time.Now()
+	bsModel, err := model.InitObjects(ctx, backstage, externalConfig, r.OwnsRuntime, r.Platform, r.Scheme)
+	observePhase(phaseModelInit, start)
 	if err != nil {
-		return ctrl.Result{}, errorAndStatus(&backstage, "failed to initialize backstage model", err)
+		return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonValidationFailed, "failed to initialize backstage model", err)
 	}
 
-	err = r.applyObjects(ctx, bsModel.RuntimeObjects)
+	if backstage.Annotations[bs.PlanOnlyAnnotation] == "true" {
+		if backstage.Status.Plan, err = r.planObjects(ctx, backstage, bsModel); err != nil {
+			return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonPlanFailed, "failed to plan backstage objects changes", err)
+		}
+		return ctrl.Result{}, nil
+	}
+	backstage.Status.Plan = nil
+
+	toApply := bsModel.RuntimeObjects
+	reportOnly := r.ReportDrift && !r.OwnsRuntime
+	if reportOnly {
+		drift, missing, err := r.detectDrift(ctx, bsModel.RuntimeObjects)
+		if err != nil {
+			return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonDriftFailed, "failed to detect backstage objects drift", err)
+		}
+		obsolete, err := r.detectObsolete(ctx, backstage, bsModel)
+		if err != nil {
+			return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonDriftFailed, "failed to detect backstage objects drift", err)
+		}
+		r.reportDrift(&backstage, append(drift, obsolete...))
+		toApply = missing
+	} else {
+		backstage.Status.Drift = nil
+	}
+
+	start = time.Now()
+	conflicts, err := r.applyObjects(ctx, &backstage, toApply)
+	observePhase(phaseApply, start)
 	if err != nil {
-		return ctrl.Result{}, errorAndStatus(&backstage, "failed to apply backstage objects", err)
+		return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonApplyFailed, "failed to apply backstage objects", err)
+	}
+
+	start = time.Now()
+	err = r.cleanObjects(ctx, &backstage, bsModel, !reportOnly)
+	observePhase(phaseCleanup, start)
+	if err != nil {
+		return ctrl.Result{}, r.errorAndStatus(&backstage, EventReasonCleanupFailed, "failed to clean backstage objects ", err)
+	}
+
+	// one-time clean-up after the upgrade from the former versions, the referred objects are not modified in drift report mode
+	if !reportOnly {
+		r.removeLegacyMetadata(ctx, externalConfig.LegacyStamped)
+	}
+
+	if len(conflicts) > 0 {
+		// the fields are owned by another field manager, it is up to the user to resolve it
+		msg := fmt.Sprintf("failed to apply, fields managed by others: %s", strings.Join(conflicts, "; "))
+		setStatusCondition(&backstage, bs.BackstageConditionTypeDeployed, metav1.ConditionFalse, bs.BackstageConditionReasonApplyConflict, msg)
+		r.recordEvent(&backstage, corev1.EventTypeWarning, EventReasonApplyConflict, msg)
+	} else {
+		setStatusCondition(&backstage, bs.BackstageConditionTypeDeployed, metav1.ConditionTrue, bs.BackstageConditionReasonDeployed, "")
 	}
 
-	if err := r.cleanObjects(ctx, backstage); err != nil {
-		return ctrl.Result{}, errorAndStatus(&backstage, "failed to clean backstage objects ", err)
+	ready, err := r.updateRuntimeStatus(ctx, &backstage)
+	if err != nil {
+		instances.set(req.NamespacedName, false)
+		return ctrl.Result{}, fmt.Errorf("failed to update runtime status %w", err)
+	}
+	instances.set(req.NamespacedName, ready)
+	if backstage.Status.URL, err = r.resolveURL(ctx, &backstage); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to resolve URL %w", err)
 	}
+	backstage.Status.ObservedGeneration = backstage.Generation
 
-	setStatusCondition(&backstage, bs.BackstageConditionTypeDeployed, metav1.ConditionTrue, bs.BackstageConditionReasonDeployed, "")
+	if !ready || len(conflicts) > 0 {
+		// Deployment/StatefulSet updates trigger reconciliation, but pod failures (e.g. image pull back-off)
+		// or releasing the conflicting fields do not necessarily change them, so check periodically
+		return ctrl.Result{RequeueAfter: notReadyRequeueInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
-func errorAndStatus(backstage *bs.Backstage, msg string, err error) error {
+// errorAndStatus sets the Deployed condition, records Warning Event with the failure and reports the instance not ready
+func (r *BackstageReconciler) errorAndStatus(backstage *bs.Backstage, reason string, msg string, err error) error {
 	setStatusCondition(backstage, bs.BackstageConditionTypeDeployed, metav1.ConditionFalse, bs.BackstageConditionReasonFailed, fmt.Sprintf("%s %s", msg, err))
+	r.recordEvent(backstage, corev1.EventTypeWarning, reason, "%s %s", msg, err)
+	reconcileFailures.WithLabelValues(backstage.Namespace, backstage.Name, reason).Inc()
+	instances.set(types.NamespacedName{Namespace: backstage.Namespace, Name: backstage.Name}, false)
 	return fmt.Errorf("%s %w", msg, err)
 }
 
-func (r *BackstageReconciler) applyObjects(ctx context.Context, objects []model.RuntimeObject) error {
+// applyObjects applies the runtime objects with server-side apply, so the fields managed by others
+// (e.g. replicas scaled by HPA, annotations added by service-ca or mesh injectors) are preserved.
+// Returns the objects which could not be applied because of conflicts with other field managers.
+// Created, updated and recreated objects are reported as Events on the Backstage object.
+func (r *BackstageReconciler) applyObjects(ctx context.Context, backstage *bs.Backstage, objects []model.RuntimeObject) ([]string, error) {
 
 	lg := log.FromContext(ctx)
 
+	var conflicts []string
 	for _, obj := range objects {
 
-		baseObject := obj.EmptyObject()
-		// do not read Secrets
-		if _, ok := obj.Object().(*corev1.Secret); ok {
-			// try to create
+		//if DBSecret - create only, it is not for update (and not to be read)
+		if _, ok := obj.(*model.DbSecret); ok {
 			if err := r.Create(ctx, obj.Object()); err != nil {
 				if !errors.IsAlreadyExists(err) {
-					return fmt.Errorf("failed to create secret: %w", err)
-				}
-				//if DBSecret - nothing to do, it is not for update
-				if _, ok := obj.(*model.DbSecret); ok {
-					continue
+					return nil, fmt.Errorf("failed to create secret: %w", err)
 				}
 			} else {
 				lg.V(1).Info("create secret ", objDispName(obj), obj.Object().GetName())
-				continue
+				r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonDbSecret, "generated database Secret %s", obj.Object().GetName())
 			}
+			continue
+		}
 
-		} else {
-			if err := r.Get(ctx, types.NamespacedName{Name: obj.Object().GetName(), Namespace: obj.Object().GetNamespace()}, baseObject); err != nil {
-				if !errors.IsNotFound(err) {
-					return fmt.Errorf("failed to get object: %w", err)
-				}
-
-				if err := r.Create(ctx, obj.Object()); err != nil {
-					return fmt.Errorf("failed to create object %w", err)
-				}
-
-				lg.V(1).Info("create object ", objDispName(obj), obj.Object().GetName())
-				continue
+		// to tell if the object is created or changed
+		live := obj.EmptyObject()
+		existed := true
+		if err := r.Get(ctx, types.NamespacedName{Name: obj.Object().GetName(), Namespace: obj.Object().GetNamespace()}, live); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get object: %w", err)
 			}
+			existed = false
 		}
 
-		if err := r.patchObject(ctx, baseObject, obj); err != nil {
+		var current client.Object
+		if existed {
+			current = live
+		}
+		err := r.applyObject(ctx, obj, current)
+		switch {
+		case err == nil:
+			lg.V(1).Info("apply object ", objDispName(obj), obj.Object().GetName())
+			r.recordApplied(backstage, obj, live, existed)
+		case errors.IsConflict(err):
+			lg.V(1).Info("conflict applying object ", objDispName(obj), obj.Object().GetName(), "cause", err)
+			conflicts = append(conflicts, fmt.Sprintf("%s %s: %s", objDispName(obj), obj.Object().GetName(), err))
+		case errors.IsInvalid(err):
 			lg.V(1).Info(
-				"failed to patch object => trying to delete it (and losing any custom labels/annotations on it) so it can be recreated upon next reconciliation...",
+				"failed to apply object => trying to delete it so it can be recreated upon next reconciliation...",
 				objDispName(obj), obj.Object().GetName(),
 				"cause", err,
 			)
-			// Some resources like StatefulSets allow patching a limited set of fields. A FieldValueForbidden error is returned.
+			// Some resources like StatefulSets allow updating a limited set of fields. A FieldValueForbidden error is returned.
 			// Some other resources like Services do not support updating the primary/secondary clusterIP || ipFamily. A FieldValueInvalid error is returned.
 			// That's why we are trying to delete them first, taking care of orphaning the dependents so that they can be retained.
 			// They will be recreated at the next reconciliation.
 			// If they cannot be recreated at the next reconciliation, the expected error will be returned.
-			if err = r.Delete(ctx, baseObject, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil {
-				return fmt.Errorf("failed to delete object %s so it can be recreated: %w", obj.Object(), err)
+			if err = r.Delete(ctx, obj.Object(), client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to delete object %s so it can be recreated: %w", obj.Object().GetName(), err)
 			}
 			lg.V(1).Info("deleted object. If you had set any custom labels/annotations on it manually, you will need to add them again",
 				objDispName(obj), obj.Object().GetName(),
 			)
-		} else {
-			lg.V(1).Info("patch object ", objDispName(obj), obj.Object().GetName())
+			kind := obj.Object().GetObjectKind().GroupVersionKind().Kind
+			r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonDeleted, "deleted %s %s to recreate it, as it can not be updated: %s",
+				kind, obj.Object().GetName(), err)
+			recreatedObjects.WithLabelValues(backstage.Namespace, backstage.Name, kind).Inc()
+		default:
+			return nil, fmt.Errorf("failed to apply object %s %s: %w", objDispName(obj), obj.Object().GetName(), err)
 		}
 	}
-	return nil
+	return conflicts, nil
 }
 
 func objDispName(obj model.RuntimeObject) string {
 	return reflect.TypeOf(obj.Object()).String()
 }
 
-func (r *BackstageReconciler) patchObject(ctx context.Context, baseObject client.Object, obj model.RuntimeObject) error {
+// recordApplied records the Event (and metrics) if the applied object has been created or changed.
+// Changed external configuration hash of Backstage Deployment means the Pods are restarted
+func (r *BackstageReconciler) recordApplied(backstage *bs.Backstage, obj model.RuntimeObject, live client.Object, existed bool) {
+	kind := obj.Object().GetObjectKind().GroupVersionKind().Kind
+	if !existed {
+		r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonCreated, "created %s %s", kind, obj.Object().GetName())
+		return
+	}
+	if live.GetResourceVersion() == obj.Object().GetResourceVersion() {
+		return
+	}
+	r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonUpdated, "updated %s %s", kind, obj.Object().GetName())
+
+	if deployment, ok := obj.Object().(*appsv1.Deployment); ok {
+		oldHash := live.(*appsv1.Deployment).Spec.Template.GetAnnotations()[model.ExtConfigHashAnnotation]
+		newHash := deployment.Spec.Template.GetAnnotations()[model.ExtConfigHashAnnotation]
+		if oldHash != newHash {
+			extConfigHashChanges.WithLabelValues(backstage.Namespace, backstage.Name).Inc()
+			r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonRestarted,
+				"external configuration changed, restarting Backstage Pods of Deployment %s", deployment.Name)
+		}
+	}
+}
 
-	//lg := log.FromContext(ctx)
+// applyObject applies the object with the Operator's field manager.
+// If the object exists (live is not nil), the fields owned by the Operator before switching to server-side apply
+// are migrated first, so the fields no longer configured are removed rather than left to the former field manager.
+// If the Operator owns the runtime, it forces the ownership of the fields it configures, so the changes made
+// by others (e.g. kubectl edit) are reverted
+func (r *BackstageReconciler) applyObject(ctx context.Context, obj model.RuntimeObject, live client.Object) error {
 
-	// restore labels and annotations
-	if baseObject.GetLabels() != nil {
-		if obj.Object().GetLabels() == nil {
-			obj.Object().SetLabels(map[string]string{})
-		}
-		for name, value := range baseObject.GetLabels() {
-			if obj.Object().GetLabels()[name] == "" {
-				obj.Object().GetLabels()[name] = value
-			}
+	if live != nil {
+		if err := r.upgradeManagedFields(ctx, live); err != nil {
+			return fmt.Errorf("failed to upgrade managed fields: %w", err)
 		}
 	}
-	if baseObject.GetAnnotations() != nil {
-		if obj.Object().GetAnnotations() == nil {
-			obj.Object().SetAnnotations(map[string]string{})
-		}
-		for name, value := range baseObject.GetAnnotations() {
-			if obj.Object().GetAnnotations()[name] == "" {
-				obj.Object().GetAnnotations()[name] = value
-			}
-		}
+
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if r.OwnsRuntime {
+		opts = append(opts, client.ForceOwnership)
 	}
+	if err := r.setApplyConfiguration(obj.Object()); err != nil {
+		return err
+	}
+	return r.Patch(ctx, obj.Object(), client.Apply, opts...)
+}
 
-	// needed for openshift.Route only, Openshift yells otherwise
-	obj.Object().SetResourceVersion(baseObject.GetResourceVersion())
-	if objectKind, ok := obj.Object().(schema.ObjectKind); ok {
-		objectKind.SetGroupVersionKind(baseObject.GetObjectKind().GroupVersionKind())
+// setApplyConfiguration prepares the object to be sent as apply configuration,
+// which has to contain apiVersion and kind and must not contain resourceVersion or managedFields
+func (r *BackstageReconciler) setApplyConfiguration(obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("failed to get GroupVersionKind: %w", err)
 	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+	return nil
+}
 
-	if err := r.Patch(ctx, obj.Object(), client.MergeFrom(baseObject)); err != nil {
-		return fmt.Errorf("failed to patch object %s: %w", objDispName(obj), err)
+// upgradeManagedFields transfers the fields of the live object owned by the client-side field managers the Operator used
+// before (see csaFieldManagers) to FieldManager, if any
+func (r *BackstageReconciler) upgradeManagedFields(ctx context.Context, live client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(live, csaFieldManagers, FieldManager)
+	if err != nil || patch == nil {
+		return err
 	}
+	return r.Patch(ctx, live.DeepCopyObject().(client.Object), client.RawPatch(types.JSONPatchType, patch))
+}
 
+// cleanObjects deletes the objects which are not desired anymore (see objectsToClean).
+// If not cleanRuntime (drift report mode), only the stale mirrors are deleted, while the runtime objects are reported (see detectObsolete)
+func (r *BackstageReconciler) cleanObjects(ctx context.Context, backstage *bs.Backstage, bsModel *model.BackstageModel, cleanRuntime bool) error {
+
+	const failedToCleanup = "failed to cleanup runtime"
+	objects, err := r.objectsToClean(ctx, *backstage, bsModel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", failedToCleanup, err)
+	}
+	for _, obj := range objects {
+		if !cleanRuntime && !isMirror(obj) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("%s failed to delete %s: %w", failedToCleanup, obj.GetName(), err)
+		}
+		kind := reflect.TypeOf(obj).Elem().Name()
+		if gvk, err := apiutil.GVKForObject(obj, r.Scheme); err == nil {
+			kind = gvk.Kind
+		}
+		r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonDeleted, "deleted %s %s, disabled or no longer referred in the spec", kind, obj.GetName())
+	}
 	return nil
 }
 
-func (r *BackstageReconciler) cleanObjects(ctx context.Context, backstage bs.Backstage) error {
+// removeLegacyMetadata removes the label and annotation set on the referred objects by the former versions
+// of the Operator (see model.ExtConfigSyncLabel), which are not used anymore. The failures are logged only,
+// the objects are cleaned up on the next reconciliation
+func (r *BackstageReconciler) removeLegacyMetadata(ctx context.Context, objects []client.Object) {
+	lg := log.FromContext(ctx)
+	for _, obj := range objects {
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		labels := obj.GetLabels()
+		delete(labels, model.ExtConfigSyncLabel)
+		obj.SetLabels(labels)
+		annotations := obj.GetAnnotations()
+		delete(annotations, model.BackstageNameAnnotation)
+		obj.SetAnnotations(annotations)
+		if err := r.Patch(ctx, obj, patch); err != nil {
+			lg.Error(err, "failed to remove legacy metadata", "object", obj.GetName(), "namespace", obj.GetNamespace())
+			continue
+		}
+		lg.V(1).Info(fmt.Sprintf("removed legacy label %s and annotation %s from external config %s", model.ExtConfigSyncLabel, model.BackstageNameAnnotation, obj.GetName()))
+	}
+}
+
+// objectsToClean returns the runtime objects (empty, with name and namespace) which have to be deleted/unowned
+// as they are disabled in the Backstage spec or not produced by the model anymore,
+// as well as the mirrors of the objects no longer referred from other namespaces
+func (r *BackstageReconciler) objectsToClean(ctx context.Context, backstage bs.Backstage, bsModel *model.BackstageModel) ([]client.Object, error) {
+
+	var objects []client.Object
+	add := func(obj client.Object, name string) {
+		obj.SetName(name)
+		obj.SetNamespace(backstage.Namespace)
+		objects = append(objects, obj)
+	}
 
-	const failedToCleanup = "failed to cleanup runtime"
 	// check if local database disabled, respective objects have to deleted/unowned
 	if !backstage.Spec.IsLocalDbEnabled() {
-		if err := r.tryToDelete(ctx, &appsv1.StatefulSet{}, model.DbStatefulSetName(backstage.Name), backstage.Namespace); err != nil {
-			return fmt.Errorf("%s %w", failedToCleanup, err)
+		add(&appsv1.StatefulSet{}, model.DbStatefulSetName(backstage.Name))
+		add(&corev1.Service{}, model.DbServiceName(backstage.Name))
+		add(&corev1.Secret{}, model.DbSecretDefaultName(backstage.Name))
+	}
+
+	// optional objects the model did not produce (disabled or removed from the spec, no default configuration)
+	// have to be deleted/unowned
+	var optional []client.Object
+	if r.Platform.IsOpenShift {
+		optional = append(optional, named(&openshift.Route{}, model.RouteName(backstage.Name)))
+	} else {
+		optional = append(optional, named(&networkingv1.Ingress{}, model.IngressName(backstage.Name)))
+	}
+	if r.Platform.HasGatewayAPI {
+		httpRoute := &unstructured.Unstructured{}
+		httpRoute.SetGroupVersionKind(model.HTTPRouteGVK)
+		optional = append(optional, named(httpRoute, model.HTTPRouteName(backstage.Name)))
+	}
+	optional = append(optional,
+		named(&corev1.PersistentVolumeClaim{}, model.DynamicPluginsCacheName(backstage.Name)),
+		named(&corev1.ConfigMap{}, model.DynamicPluginsCacheName(backstage.Name)),
+		named(&corev1.ConfigMap{}, model.InlineAppConfigName(backstage.Name)))
+	for _, obj := range optional {
+		if !inModel(bsModel, obj) {
+			add(obj, obj.GetName())
 		}
-		if err := r.tryToDelete(ctx, &corev1.Service{}, model.DbServiceName(backstage.Name), backstage.Namespace); err != nil {
-			return fmt.Errorf("%s %w", failedToCleanup, err)
+	}
+
+	mirrors, err := r.staleMirrors(ctx, backstage, bsModel)
+	if err != nil {
+		return nil, err
+	}
+	return append(objects, mirrors...), nil
+}
+
+func named(obj client.Object, name string) client.Object {
+	obj.SetName(name)
+	return obj
+}
+
+// inModel returns true if the model contains the runtime object of the same type (kind for unstructured) and name
+func inModel(bsModel *model.BackstageModel, obj client.Object) bool {
+	for _, ro := range bsModel.RuntimeObjects {
+		mobj := ro.Object()
+		if reflect.TypeOf(mobj) != reflect.TypeOf(obj) || mobj.GetName() != obj.GetName() {
+			continue
 		}
-		if err := r.tryToDelete(ctx, &corev1.Secret{}, model.DbSecretDefaultName(backstage.Name), backstage.Namespace); err != nil {
-			return fmt.Errorf("%s %w", failedToCleanup, err)
+		if u, ok := obj.(*unstructured.Unstructured); ok && mobj.(*unstructured.Unstructured).GetKind() != u.GetKind() {
+			continue
 		}
+		return true
+	}
+	return false
+}
+
+func isMirror(obj client.Object) bool {
+	return obj.GetLabels()[model.MirrorLabel] == "true"
+}
+
+// staleMirrors returns the mirrors of the Backstage instance (see model.Mirror) which are not in the model anymore
+func (r *BackstageReconciler) staleMirrors(ctx context.Context, backstage bs.Backstage, bsModel *model.BackstageModel) ([]client.Object, error) {
+
+	current := map[string]bool{}
+	for _, obj := range bsModel.ExternalConfig.Mirrors {
+		current[fmt.Sprintf("%T/%s", obj, obj.GetName())] = true
 	}
 
-	//// check if route disabled, respective objects have to deleted/unowned
-	if r.IsOpenShift && !backstage.Spec.IsRouteEnabled() {
-		if err := r.tryToDelete(ctx, &openshift.Route{}, model.RouteName(backstage.Name), backstage.Namespace); err != nil {
-			return fmt.Errorf("%s %w", failedToCleanup, err)
+	mirrors, err := r.listMirrors(ctx, backstage)
+	if err != nil {
+		return nil, err
+	}
+	var stale []client.Object
+	for _, obj := range mirrors {
+		if !current[fmt.Sprintf("%T/%s", obj, obj.GetName())] {
+			stale = append(stale, obj)
 		}
 	}
+	return stale, nil
+}
 
+// cleanUngrantedMirrors deletes the mirrors of the Backstage instance whose original objects are not allowed
+// to be referred by any BackstageReferenceGrant anymore. Called if the preprocessing fails (e.g. the grant is revoked),
+// so the model is not initialized and the stale mirrors can not be found comparing to it
+func (r *BackstageReconciler) cleanUngrantedMirrors(ctx context.Context, backstage *bs.Backstage) error {
+
+	mirrors, err := r.listMirrors(ctx, *backstage)
+	if err != nil {
+		return err
+	}
+	for _, obj := range mirrors {
+		kind := reflect.TypeOf(obj).Elem().Name()
+		// <namespace>/<name> of the original object, the mirror is not granted if it can not be told
+		from := strings.SplitN(obj.GetAnnotations()[model.MirroredFromAnnotation], "/", 2)
+		if len(from) == 2 {
+			granted, err := referenceGranted(ctx, r.Client, backstage.Namespace, kind, from[0], from[1])
+			if err != nil {
+				return err
+			}
+			if granted {
+				continue
+			}
+		}
+		if err := r.Delete(ctx, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to delete mirror %s %s: %w", kind, obj.GetName(), err)
+		}
+		r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonDeleted, "deleted %s %s, the mirrored object is not granted anymore", kind, obj.GetName())
+	}
 	return nil
 }
 
-// tryToDelete tries to delete the object by name and namespace, does not throw error if object not found
-func (r *BackstageReconciler) tryToDelete(ctx context.Context, obj client.Object, name string, ns string) error {
-	obj.SetName(name)
-	obj.SetNamespace(ns)
-	if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete %s: %w", name, err)
+// listMirrors returns the mirrored ConfigMaps and Secrets of the Backstage instance
+func (r *BackstageReconciler) listMirrors(ctx context.Context, backstage bs.Backstage) ([]client.Object, error) {
+
+	var mirrors []client.Object
+	selector := client.MatchingLabels{model.MirrorLabel: "true", utils.KubeInstanceLabel: backstage.Name}
+	cms := corev1.ConfigMapList{}
+	if err := r.List(ctx, &cms, client.InNamespace(backstage.Namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list mirrored ConfigMaps: %w", err)
 	}
-	return nil
+	for i := range cms.Items {
+		mirrors = append(mirrors, &cms.Items[i])
+	}
+	secrets := corev1.SecretList{}
+	if err := r.List(ctx, &secrets, client.InNamespace(backstage.Namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list mirrored Secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		mirrors = append(mirrors, &secrets.Items[i])
+	}
+	return mirrors, nil
 }
 
 func setStatusCondition(backstage *bs.Backstage, condType bs.BackstageConditionType, status metav1.ConditionStatus, reason bs.BackstageConditionReason, msg string) {
@@ -293,65 +599,167 @@ func setStatusCondition(backstage *bs.Backstage, condType bs.BackstageConditionT
 	})
 }
 
-// requestByLabel returns a request with current Namespace and Backstage Object name taken from label
-// or empty request object if label not found
-func (r *BackstageReconciler) requestByLabel(ctx context.Context, object client.Object) []reconcile.Request {
+// externalConfigRefIndex indexes Backstage instances by the external ConfigMaps and Secrets they refer
+// (see externalConfigRefs), so the instances can be found by the changed object
+const externalConfigRefIndex = ".spec.externalConfigRefs"
+
+// externalConfigRefs returns the index values (<kind>/<namespace>/<name>) of the external ConfigMaps and Secrets
+// referred by the Backstage object
+func externalConfigRefs(obj client.Object) []string {
+	backstage, ok := obj.(*bs.Backstage)
+	if !ok {
+		return nil
+	}
+	var refs []string
+	for _, kind := range []string{"ConfigMap", "Secret"} {
+		for _, ref := range backstage.Spec.ExternalConfigRefs(kind) {
+			refs = append(refs, externalConfigRef(kind, backstage.RefNamespace(ref), ref.Name))
+		}
+	}
+	return refs
+}
+
+func externalConfigRef(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// requestByReference returns the requests for all the Backstage instances (in any Namespace) which refer
+// the ConfigMap or Secret (kind) and have to be reconciled because of its change (see externalConfigChanged)
+func (r *BackstageReconciler) requestByReference(ctx context.Context, kind string, object client.Object) []reconcile.Request {
 
 	lg := log.FromContext(ctx)
 
-	backstageName := object.GetAnnotations()[model.BackstageNameAnnotation]
-	if backstageName == "" {
-		//lg.V(1).Info(fmt.Sprintf("warning: %s annotation is not defined for %s, Backstage instances will not be reconciled in this loop", model.BackstageNameAnnotation, object.GetName()))
+	backstages := bs.BackstageList{}
+	if err := r.List(ctx, &backstages,
+		client.MatchingFields{externalConfigRefIndex: externalConfigRef(kind, object.GetNamespace(), object.GetName())}); err != nil {
+		lg.Error(err, "request by reference failed, list Backstages ")
 		return []reconcile.Request{}
 	}
 
-	nn := types.NamespacedName{
-		Namespace: object.GetNamespace(),
-		Name:      backstageName,
+	requests := []reconcile.Request{}
+	for _, backstage := range backstages.Items {
+		if r.externalConfigChanged(ctx, kind, object, backstage) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: backstage.Name, Namespace: backstage.Namespace}})
+		}
 	}
+	return requests
+}
 
-	backstage := bs.Backstage{}
-	if err := r.Get(ctx, nn, &backstage); err != nil {
-		if !errors.IsNotFound(err) {
-			lg.Error(err, "request by label failed, get Backstage ")
+// AutoSyncEnvVar sets the default of spec.application.restartOnConfigChange for all the Backstage instances.
+// Deprecated: use spec.application.restartOnConfigChange instead
+const AutoSyncEnvVar = "EXT_CONF_SYNC_backstage"
+
+// restartOnConfigChangeDefault returns the value of AutoSyncEnvVar, true if not set or invalid
+func restartOnConfigChangeDefault() bool {
+	if autoSyncStr, ok := os.LookupEnv(AutoSyncEnvVar); ok {
+		if autoSync, err := strconv.ParseBool(autoSyncStr); err == nil {
+			return autoSync
 		}
-		return []reconcile.Request{}
+	}
+	return true
+}
+
+// externalConfigChanged returns true if the Backstage instance restarts on the object change and the config hash
+// differs from the one of the deployed Pods, or if the object is a hot reloaded app-config of another namespace,
+// whose mirror has to be updated (not hashed, so the hash does not tell)
+func (r *BackstageReconciler) externalConfigChanged(ctx context.Context, kind string, object client.Object, backstage bs.Backstage) bool {
+
+	lg := log.FromContext(ctx).WithValues("backstage", backstage.Name)
+
+	if object.GetNamespace() != backstage.Namespace && backstage.IsHotReloaded(kind, object.GetNamespace(), object.GetName()) {
+		lg.V(1).Info("enqueuing reconcile to update the mirror of hot reloaded", kind, object.GetName())
+		return true
+	}
+
+	if !backstage.IsRestartOnChange(kind, object.GetNamespace(), object.GetName(), restartOnConfigChangeDefault()) {
+		lg.V(1).Info("request by reference, restart on change is disabled", kind, object.GetName())
+		return false
 	}
 
 	ec, err := r.preprocessSpec(ctx, backstage)
 	if err != nil {
-		lg.Error(err, "request by label failed, preprocess Backstage ")
-		return []reconcile.Request{}
+		lg.Error(err, "request by reference failed, preprocess Backstage ")
+		return false
 	}
 
 	deploy := &appsv1.Deployment{}
-	if err := r.Get(ctx, types.NamespacedName{Name: model.DeploymentName(backstage.Name), Namespace: object.GetNamespace()}, deploy); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Name: model.DeploymentName(backstage.Name), Namespace: backstage.Namespace}, deploy); err != nil {
 		if errors.IsNotFound(err) {
-			lg.V(1).Info("request by label, deployment not found", "name", model.DeploymentName(backstage.Name))
+			lg.V(1).Info("request by reference, deployment not found", "name", model.DeploymentName(backstage.Name))
 		} else {
-			lg.Error(err, "request by label failed, get Deployment ", "error ", err)
+			lg.Error(err, "request by reference failed, get Deployment ", "error ", err)
 		}
-		return []reconcile.Request{}
+		return false
 	}
 
 	newHash := ec.GetHash()
 	oldHash := deploy.Spec.Template.ObjectMeta.GetAnnotations()[model.ExtConfigHashAnnotation]
 	if newHash == oldHash {
-		lg.V(1).Info("request by label, hash are equal", "hash", newHash)
+		lg.V(1).Info("request by reference, hash are equal", "hash", newHash)
+		return false
+	}
+
+	lg.V(1).Info("enqueuing reconcile for", kind, object.GetName(), "new hash: ", newHash, "old hash: ", oldHash)
+	return true
+}
+
+// requestByGrant returns the requests for all the Backstage instances referring objects of the namespace
+// of the BackstageReferenceGrant, so granting or revoking the access takes effect promptly
+func (r *BackstageReconciler) requestByGrant(ctx context.Context, object client.Object) []reconcile.Request {
+
+	backstages := bs.BackstageList{}
+	if err := r.List(ctx, &backstages); err != nil {
+		log.FromContext(ctx).Error(err, "request by grant failed, list Backstages ")
 		return []reconcile.Request{}
 	}
 
-	lg.V(1).Info("enqueuing reconcile for", object.GetObjectKind().GroupVersionKind().Kind, object.GetName(), "new hash: ", newHash, "old hash: ", oldHash)
-	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: backstage.Name, Namespace: object.GetNamespace()}}}
+	requests := []reconcile.Request{}
+	for _, backstage := range backstages.Items {
+		if refersNamespace(backstage, object.GetNamespace()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: backstage.Name, Namespace: backstage.Namespace}})
+		}
+	}
+	return requests
+}
 
+// refersNamespace returns true if the Backstage instance refers external config objects of another namespace ns
+func refersNamespace(backstage bs.Backstage, ns string) bool {
+	if backstage.Namespace == ns {
+		return false
+	}
+	for _, kind := range []string{"ConfigMap", "Secret"} {
+		for _, ref := range backstage.Spec.ExternalConfigRefs(kind) {
+			if backstage.RefNamespace(ref) == ns {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestByKubeLabels returns a request for the Backstage instance the runtime object belongs to,
+// according to its app.kubernetes.io labels, or empty request object if the labels not found
+func (r *BackstageReconciler) requestByKubeLabels(_ context.Context, object client.Object) []reconcile.Request {
+	labels := object.GetLabels()
+	if labels[utils.KubeNameLabel] != utils.KubeNameValue || labels[utils.KubeInstanceLabel] == "" {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: labels[utils.KubeInstanceLabel], Namespace: object.GetNamespace()}}}
+}
+
+// apiReader returns the reader bypassing the cache, or the Client if not set (e.g. in tests)
+func (r *BackstageReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BackstageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
-	pred, err := predicate.LabelSelectorPredicate(watchedConfigSelector)
-	if err != nil {
-		return fmt.Errorf("failed to construct the predicate for matching secrets. This should not happen: %w", err)
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &bs.Backstage{}, externalConfigRefIndex, externalConfigRefs); err != nil {
+		return fmt.Errorf("failed to index Backstage external config references: %w", err)
 	}
 
 	secretMeta := &metav1.PartialObjectMetadata{}
@@ -373,24 +781,100 @@ func (r *BackstageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		WatchesMetadata(
 			secretMeta,
 			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
-				return r.requestByLabel(ctx, o)
+				return r.requestByReference(ctx, "Secret", o)
 			}),
-			builder.WithPredicates(pred, predicate.Funcs{
-				DeleteFunc: func(e event.DeleteEvent) bool { return true },
-				UpdateFunc: func(e event.UpdateEvent) bool { return true },
-				//CreateFunc: func(e event.CreateEvent) bool { return true },
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(e event.CreateEvent) bool { return false },
 			}),
 		).
 		WatchesMetadata(
 			configMapMeta,
 			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
-				return r.requestByLabel(ctx, o)
+				return r.requestByReference(ctx, "ConfigMap", o)
 			}),
-			builder.WithPredicates(pred, predicate.Funcs{
-				DeleteFunc: func(e event.DeleteEvent) bool { return true },
-				UpdateFunc: func(e event.UpdateEvent) bool { return true },
-				//CreateFunc: func(e event.CreateEvent) bool { return true },
-			}))
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(e event.CreateEvent) bool { return false },
+			})).
+		Watches(
+			&appsv1.Deployment{},
+			handler.EnqueueRequestsFromMapFunc(r.requestByKubeLabels),
+		).
+		Watches(
+			&appsv1.StatefulSet{},
+			handler.EnqueueRequestsFromMapFunc(r.requestByKubeLabels),
+		).
+		Watches(
+			&bs.BackstageReferenceGrant{},
+			handler.EnqueueRequestsFromMapFunc(r.requestByGrant),
+		)
+
+	// to update the status URL once the host is admitted
+	if r.Platform.IsOpenShift {
+		b = b.Watches(
+			&openshift.Route{},
+			handler.EnqueueRequestsFromMapFunc(r.requestByKubeLabels),
+		)
+	} else {
+		b = b.Watches(
+			&networkingv1.Ingress{},
+			handler.EnqueueRequestsFromMapFunc(r.requestByKubeLabels),
+		)
+	}
+	if r.Platform.HasGatewayAPI {
+		httpRoute := &unstructured.Unstructured{}
+		httpRoute.SetGroupVersionKind(model.HTTPRouteGVK)
+		b = b.Watches(
+			httpRoute,
+			handler.EnqueueRequestsFromMapFunc(r.requestByKubeLabels),
+		)
+	}
+
+	// to revert the changes made to the runtime objects and recreate deleted ones promptly
+	if r.OwnsRuntime {
+		for _, obj := range r.ownedObjects() {
+			opts := []builder.OwnsOption{builder.WithPredicates(ignoreStatusUpdates)}
+			// not to cache the content of all the ConfigMaps and Secrets of the cluster,
+			// the metadata is enough to enqueue the owner
+			switch obj.(type) {
+			case *corev1.ConfigMap, *corev1.Secret:
+				opts = append(opts, builder.OnlyMetadata)
+			}
+			b = b.Owns(obj, opts...)
+		}
+	}
 
 	return b.Complete(r)
 }
+
+// ownedObjects returns empty objects of all the kinds the model may produce on current cluster
+func (r *BackstageReconciler) ownedObjects() []client.Object {
+	objects := []client.Object{
+		&appsv1.Deployment{},
+		&appsv1.StatefulSet{},
+		&corev1.Service{},
+		&corev1.ConfigMap{},
+		&corev1.Secret{},
+		&corev1.PersistentVolumeClaim{},
+	}
+	if r.Platform.IsOpenShift {
+		objects = append(objects, &openshift.Route{})
+	} else {
+		objects = append(objects, &networkingv1.Ingress{})
+	}
+	if r.Platform.HasGatewayAPI {
+		httpRoute := &unstructured.Unstructured{}
+		httpRoute.SetGroupVersionKind(model.HTTPRouteGVK)
+		objects = append(objects, httpRoute)
+	}
+	return objects
+}
+
+// ignoreStatusUpdates filters out the update events changing the object's status only.
+// Status updates do not change metadata.generation, labels or annotations,
+// while the kinds not using the generation (e.g. ConfigMap, Service) pass through
+var ignoreStatusUpdates = predicate.Or(
+	predicate.GenerationChangedPredicate{},
+	predicate.LabelChangedPredicate{},
+	predicate.AnnotationChangedPredicate{},
+	predicate.NewPredicateFuncs(func(obj client.Object) bool { return obj.GetGeneration() == 0 }),
+)
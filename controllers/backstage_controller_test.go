@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestApplyObjects(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme()}
+
+	// DB Secret is created once and never updated
+	dbSecret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: model.DbSecretDefaultName("bs1"), Namespace: "ns1"},
+		StringData: map[string]string{"POSTGRES_PASSWORD": "custom"}}
+	assert.NoError(t, rc.Create(ctx, &dbSecret))
+
+	bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+
+	conflicts, err := rc.applyObjects(ctx, &backstage, bsModel.RuntimeObjects)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	deployment := appsv1.Deployment{}
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Name: model.DeploymentName("bs1"), Namespace: "ns1"}, &deployment))
+	// apply configuration is typed
+	assert.Equal(t, "apps/v1", deployment.APIVersion)
+	assert.Equal(t, "Deployment", deployment.Kind)
+
+	secret := corev1.Secret{}
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Name: model.DbSecretDefaultName("bs1"), Namespace: "ns1"}, &secret))
+	assert.Equal(t, "custom", secret.StringData["POSTGRES_PASSWORD"])
+}
+
+func TestIgnoreStatusUpdates(t *testing.T) {
+
+	oldDeploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "backstage-bs1", Generation: 1}}
+	newDeploy := oldDeploy.DeepCopy()
+	newDeploy.Status.AvailableReplicas = 1
+	assert.False(t, ignoreStatusUpdates.Update(event.UpdateEvent{ObjectOld: oldDeploy, ObjectNew: newDeploy}))
+
+	newDeploy.Annotations = map[string]string{"edited": "true"}
+	assert.True(t, ignoreStatusUpdates.Update(event.UpdateEvent{ObjectOld: oldDeploy, ObjectNew: newDeploy}))
+
+	newDeploy = oldDeploy.DeepCopy()
+	newDeploy.Generation = 2
+	assert.True(t, ignoreStatusUpdates.Update(event.UpdateEvent{ObjectOld: oldDeploy, ObjectNew: newDeploy}))
+
+	// no generation
+	oldCm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}
+	newCm := oldCm.DeepCopy()
+	newCm.Data = map[string]string{"key": "value"}
+	assert.True(t, ignoreStatusUpdates.Update(event.UpdateEvent{ObjectOld: oldCm, ObjectNew: newCm}))
+
+	assert.True(t, ignoreStatusUpdates.Delete(event.DeleteEvent{Object: oldDeploy}))
+}
+
+func TestCleanRemovedIngress(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: bs.BackstageSpec{Application: &bs.Application{
+			Ingress: &bs.Ingress{Host: "backstage.example.com"},
+		}}}
+
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme()}
+
+	cleaned := func() []string {
+		bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+		assert.NoError(t, err)
+		objects, err := rc.objectsToClean(ctx, backstage, bsModel)
+		assert.NoError(t, err)
+		var names []string
+		for _, obj := range objects {
+			names = append(names, kind(obj)+"/"+obj.GetName())
+		}
+		return names
+	}
+
+	assert.NotContains(t, cleaned(), "Ingress/"+model.IngressName("bs1"))
+
+	// block removed, there is no default Ingress
+	backstage.Spec.Application.Ingress = nil
+	assert.Contains(t, cleaned(), "Ingress/"+model.IngressName("bs1"))
+}
+
+func TestCleanRemovedHTTPRoute(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: bs.BackstageSpec{Application: &bs.Application{
+			HTTPRoute: &bs.HTTPRoute{ParentRef: &bs.GatewayRef{Name: "gateway"}},
+		}}}
+
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme(), Platform: model.Platform{HasGatewayAPI: true}}
+
+	httpRouteCleaned := func() bool {
+		bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{HasGatewayAPI: true}, rc.Scheme)
+		assert.NoError(t, err)
+		objects, err := rc.objectsToClean(ctx, backstage, bsModel)
+		assert.NoError(t, err)
+		for _, obj := range objects {
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetKind() == "HTTPRoute" {
+				assert.Equal(t, model.HTTPRouteName("bs1"), u.GetName())
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, httpRouteCleaned())
+
+	// block removed, there is no default HTTPRoute
+	backstage.Spec.Application.HTTPRoute = nil
+	assert.True(t, httpRouteCleaned())
+}
+
+// forceRecorder records whether the objects are applied forcing the ownership
+type forceRecorder struct {
+	MockClient
+	forced map[string]bool
+}
+
+func (c forceRecorder) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.forced[obj.GetName()] = (&client.PatchOptions{}).ApplyOptions(opts).Force != nil
+	return c.MockClient.Patch(ctx, obj, patch, opts...)
+}
+
+func TestApplyForcesOwnershipIfOwnsRuntime(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+
+	for _, owns := range []bool{true, false} {
+		c := forceRecorder{MockClient: NewMockClient(), forced: map[string]bool{}}
+		rc := BackstageReconciler{Client: c, Scheme: renderTestScheme(), OwnsRuntime: owns}
+
+		bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), owns, model.Platform{}, rc.Scheme)
+		assert.NoError(t, err)
+		_, err = rc.applyObjects(ctx, &backstage, bsModel.RuntimeObjects)
+		assert.NoError(t, err)
+
+		// the changes made by others (e.g. kubectl edit) are reverted only if the Operator owns the runtime
+		assert.Equal(t, owns, c.forced[model.DeploymentName("bs1")])
+	}
+}
@@ -0,0 +1,202 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+)
+
+//+kubebuilder:webhook:path=/validate-rhdh-redhat-com-v1alpha2-backstage,mutating=false,failurePolicy=fail,sideEffects=None,groups=rhdh.redhat.com,resources=backstages,verbs=create;update,versions=v1alpha2,name=vbackstage.kb.io,admissionReviewVersions=v1
+
+// SetupBackstageWebhookWithManager registers the Backstage webhooks with the manager:
+// the conversion webhook (v1alpha2 is the conversion Hub) and the validating webhook
+func SetupBackstageWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&bs.Backstage{}).
+		WithValidator(&BackstageValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// BackstageValidator validates Backstage CRs on creation and update.
+// It reads the ConfigMaps and Secrets the CR refers to (never modifies them, unlike preprocessSpec)
+// and runs the same checks as model.InitObjects does, so the mistakes are reported at admission time
+// instead of as DeployFailed condition.
+type BackstageValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &BackstageValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *BackstageValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+// The references the old object has as well are only warned about if broken (e.g. the ConfigMap was deleted meanwhile),
+// not to block the updates unrelated to them (including the removal of the finalizers).
+func (v *BackstageValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, _ := oldObj.(*bs.Backstage)
+	return v.validate(ctx, newObj, old)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *BackstageValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *BackstageValidator) validate(ctx context.Context, obj runtime.Object, old *bs.Backstage) (admission.Warnings, error) {
+	backstage, ok := obj.(*bs.Backstage)
+	if !ok {
+		return nil, fmt.Errorf("expected a Backstage object but got %T", obj)
+	}
+
+	externalConfig, warnings, errs := v.readExternalConfig(ctx, backstage, old)
+	errs = append(errs, model.ValidateSpec(backstage.Spec, externalConfig)...)
+	if len(errs) > 0 {
+		return warnings, errors.NewInvalid(bs.GroupVersion.WithKind("Backstage").GroupKind(), backstage.Name, errs)
+	}
+	return warnings, nil
+}
+
+// readExternalConfig reads the ConfigMaps and Secrets referenced by the Backstage spec,
+// returning field errors for the ones which do not exist or are of other namespaces not allowing the reference.
+// The objects referenced by the old version of the Backstage (if any) as well are returned as warnings instead.
+func (v *BackstageValidator) readExternalConfig(ctx context.Context, backstage *bs.Backstage, old *bs.Backstage) (model.ExternalConfig, admission.Warnings, field.ErrorList) {
+	result := model.NewExternalConfig()
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	oldRefs := sets.New[string]()
+	if old != nil {
+		for _, kind := range []string{"ConfigMap", "Secret"} {
+			for _, ref := range old.Spec.ExternalConfigRefs(kind) {
+				oldRefs.Insert(fmt.Sprintf("%s/%s/%s", kind, old.RefNamespace(ref), ref.Name))
+			}
+		}
+	}
+	// reports the error of the reference to the object
+	report := func(obj client.Object, namespace, name string, err *field.Error) {
+		if oldRefs.Has(fmt.Sprintf("%s/%s/%s", objectKind(obj), namespace, name)) {
+			warnings = append(warnings, err.Error())
+			return
+		}
+		errs = append(errs, err)
+	}
+
+	readFrom := func(obj client.Object, namespace, name string, path *field.Path) {
+		if err := v.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+			switch {
+			case errors.IsNotFound(err):
+				report(obj, namespace, name, field.NotFound(path, name))
+			case errors.IsForbidden(err):
+				warnings = append(warnings, fmt.Sprintf("%s: can not read %s: %s", path, name, err))
+			default:
+				errs = append(errs, field.InternalError(path, err))
+			}
+		}
+	}
+	read := func(obj client.Object, name string, path *field.Path) {
+		readFrom(obj, backstage.Namespace, name, path)
+	}
+	readRef := func(obj client.Object, ref bs.ObjectKeyRef, path *field.Path) {
+		ns := backstage.RefNamespace(ref)
+		if ns != backstage.Namespace {
+			kind := objectKind(obj)
+			granted, err := referenceGranted(ctx, v.Client, backstage.Namespace, kind, ns, ref.Name)
+			if err != nil {
+				errs = append(errs, field.InternalError(path.Child("namespace"), err))
+				return
+			}
+			if !granted {
+				report(obj, ns, ref.Name, field.Forbidden(path.Child("namespace"),
+					fmt.Sprintf("%s %s/%s is not allowed to be referred by any BackstageReferenceGrant", kind, ns, ref.Name)))
+				return
+			}
+		}
+		readFrom(obj, ns, ref.Name, path.Child("name"))
+	}
+
+	spec := backstage.Spec
+	specPath := field.NewPath("spec")
+
+	if spec.RawRuntimeConfig != nil {
+		path := specPath.Child("rawRuntimeConfig")
+		if spec.RawRuntimeConfig.BackstageConfigName != "" {
+			read(&corev1.ConfigMap{}, spec.RawRuntimeConfig.BackstageConfigName, path.Child("backstageConfig"))
+		}
+		if spec.RawRuntimeConfig.LocalDbConfigName != "" {
+			read(&corev1.ConfigMap{}, spec.RawRuntimeConfig.LocalDbConfigName, path.Child("localDbConfig"))
+		}
+	}
+
+	if spec.Application == nil {
+		return result, warnings, errs
+	}
+	appPath := specPath.Child("application")
+
+	if spec.Application.AppConfig != nil {
+		for i, ref := range spec.Application.AppConfig.ConfigMaps {
+			readRef(&corev1.ConfigMap{}, ref, appPath.Child("appConfig", "configMaps").Index(i))
+		}
+	}
+	if spec.Application.ExtraFiles != nil {
+		for i, ref := range spec.Application.ExtraFiles.ConfigMaps {
+			readRef(&corev1.ConfigMap{}, ref, appPath.Child("extraFiles", "configMaps").Index(i))
+		}
+		for i, ref := range spec.Application.ExtraFiles.Secrets {
+			readRef(&corev1.Secret{}, ref, appPath.Child("extraFiles", "secrets").Index(i))
+		}
+	}
+	if spec.Application.ExtraEnvs != nil {
+		for i, ref := range spec.Application.ExtraEnvs.ConfigMaps {
+			readRef(&corev1.ConfigMap{}, ref, appPath.Child("extraEnvs", "configMaps").Index(i))
+		}
+		for i, ref := range spec.Application.ExtraEnvs.Secrets {
+			readRef(&corev1.Secret{}, ref, appPath.Child("extraEnvs", "secrets").Index(i))
+		}
+	}
+	if spec.Application.DynamicPluginsConfigMapName != "" {
+		read(&result.DynamicPlugins, spec.Application.DynamicPluginsConfigMapName, appPath.Child("dynamicPluginsConfigMapName"))
+	}
+	if registry := spec.Application.DynamicPluginsRegistry; registry != nil {
+		path := appPath.Child("dynamicPluginsRegistry")
+		if registry.Npmrc != nil {
+			readRef(&result.DynamicPluginsNpmrc, *registry.Npmrc, path.Child("npmrc"))
+		}
+		if registry.CABundle != nil {
+			readRef(&result.DynamicPluginsCABundle, *registry.CABundle, path.Child("caBundle"))
+		}
+		if registry.OCIAuth != nil {
+			readRef(&result.DynamicPluginsOCIAuth, *registry.OCIAuth, path.Child("ociAuth"))
+		}
+	}
+
+	return result, warnings, errs
+}
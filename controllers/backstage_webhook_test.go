@@ -0,0 +1,202 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func webhookBackstage(app *bs.Application) *bs.Backstage {
+	return &bs.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec:       bs.BackstageSpec{Application: app},
+	}
+}
+
+func causeFields(err error) []string {
+	var fields []string
+	for _, cause := range err.(*errors.StatusError).ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func TestValidateWebhookValid(t *testing.T) {
+	ctx := context.TODO()
+	client := NewMockClient()
+	v := BackstageValidator{Client: client}
+
+	cm := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "ns1"}}
+	assert.NoError(t, client.Create(ctx, &cm))
+	plugins := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "plugins", Namespace: "ns1"},
+		Data: map[string]string{model.DynamicPluginsFile: "plugins: []"}}
+	assert.NoError(t, client.Create(ctx, &plugins))
+
+	_, err := v.ValidateCreate(ctx, webhookBackstage(nil))
+	assert.NoError(t, err)
+
+	_, err = v.ValidateCreate(ctx, webhookBackstage(&bs.Application{
+		AppConfig:                   &bs.AppConfig{ConfigMaps: []bs.ObjectKeyRef{{Name: "app-config"}}},
+		DynamicPluginsConfigMapName: "plugins",
+	}))
+	assert.NoError(t, err)
+}
+
+func TestValidateWebhookMissingConfigMap(t *testing.T) {
+	v := BackstageValidator{Client: NewMockClient()}
+
+	_, err := v.ValidateCreate(context.TODO(), webhookBackstage(&bs.Application{
+		AppConfig: &bs.AppConfig{ConfigMaps: []bs.ObjectKeyRef{{Name: "app-config"}}},
+	}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.appConfig.configMaps[0].name"}, causeFields(err))
+}
+
+func TestValidateWebhookUpdateKeepsBrokenReference(t *testing.T) {
+	v := BackstageValidator{Client: NewMockClient()}
+
+	old := webhookBackstage(&bs.Application{
+		AppConfig: &bs.AppConfig{ConfigMaps: []bs.ObjectKeyRef{{Name: "app-config"}}},
+	})
+
+	// the ConfigMap deleted after the creation does not block the update
+	updated := old.DeepCopy()
+	updated.Finalizers = nil
+	warnings, err := v.ValidateUpdate(context.TODO(), old, updated)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "spec.application.appConfig.configMaps[0].name")
+
+	// the new reference is validated
+	updated.Spec.Application.ExtraEnvs = &bs.ExtraEnvs{ConfigMaps: []bs.ObjectKeyRef{{Name: "envs"}}}
+	_, err = v.ValidateUpdate(context.TODO(), old, updated)
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.extraEnvs.configMaps[0].name"}, causeFields(err))
+}
+
+func TestValidateWebhookCrossNamespaceNotGranted(t *testing.T) {
+	ctx := context.TODO()
+	client := NewMockClient()
+	v := BackstageValidator{Client: client}
+
+	cm := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "branding", Namespace: "shared"}}
+	assert.NoError(t, client.Create(ctx, &cm))
+	app := &bs.Application{
+		AppConfig: &bs.AppConfig{ConfigMaps: []bs.ObjectKeyRef{{Name: "branding", Namespace: "shared"}}},
+	}
+
+	_, err := v.ValidateCreate(ctx, webhookBackstage(app))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.appConfig.configMaps[0].namespace"}, causeFields(err))
+
+	grant := bs.BackstageReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "grant", Namespace: "shared"},
+		Spec: bs.BackstageReferenceGrantSpec{
+			From: []bs.ReferenceGrantFrom{{Namespace: "ns1"}},
+			To:   []bs.ReferenceGrantTo{{Kind: "ConfigMap", Name: "branding"}},
+		},
+	}
+	assert.NoError(t, client.Create(ctx, &grant))
+	_, err = v.ValidateCreate(ctx, webhookBackstage(app))
+	assert.NoError(t, err)
+}
+
+func TestValidateWebhookSecretFileNoKey(t *testing.T) {
+	ctx := context.TODO()
+	client := NewMockClient()
+	v := BackstageValidator{Client: client}
+
+	secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "ns1"}}
+	assert.NoError(t, client.Create(ctx, &secret))
+
+	_, err := v.ValidateUpdate(ctx, nil, webhookBackstage(&bs.Application{
+		ExtraFiles: &bs.ExtraFiles{Secrets: []bs.ObjectKeyRef{{Name: "secret"}}},
+	}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.extraFiles.secrets[0].key"}, causeFields(err))
+}
+
+func TestValidateWebhookDynamicPluginsNoKey(t *testing.T) {
+	ctx := context.TODO()
+	client := NewMockClient()
+	v := BackstageValidator{Client: client}
+
+	plugins := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "plugins", Namespace: "ns1"},
+		Data: map[string]string{"plugins.yaml": "plugins: []"}}
+	assert.NoError(t, client.Create(ctx, &plugins))
+
+	_, err := v.ValidateCreate(ctx, webhookBackstage(&bs.Application{DynamicPluginsConfigMapName: "plugins"}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.dynamicPluginsConfigMapName"}, causeFields(err))
+
+	// missing ConfigMap is reported once
+	_, err = v.ValidateCreate(ctx, webhookBackstage(&bs.Application{DynamicPluginsConfigMapName: "absent"}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.dynamicPluginsConfigMapName"}, causeFields(err))
+}
+
+func TestValidateWebhookDynamicPluginsConfig(t *testing.T) {
+	v := BackstageValidator{Client: NewMockClient()}
+
+	_, err := v.ValidateCreate(context.TODO(), webhookBackstage(&bs.Application{
+		DynamicPlugins: []bs.DynamicPlugin{
+			{Package: "./dynamic-plugins/dist/plugin-a", PluginConfig: &apiextensionsv1.JSON{Raw: []byte(`{"a": 1}`)}},
+			{Package: "./dynamic-plugins/dist/plugin-b", PluginConfig: &apiextensionsv1.JSON{Raw: []byte(`["b"]`)}},
+		},
+	}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.dynamicPlugins[1].pluginConfig"}, causeFields(err))
+}
+
+func TestValidateWebhookDynamicPluginsRegistryNoKey(t *testing.T) {
+	ctx := context.TODO()
+	client := NewMockClient()
+	v := BackstageValidator{Client: client}
+
+	npmrc := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "npmrc", Namespace: "ns1"},
+		Data: map[string][]byte{"config": []byte("registry=https://npm.example.com")}}
+	assert.NoError(t, client.Create(ctx, &npmrc))
+
+	_, err := v.ValidateCreate(ctx, webhookBackstage(&bs.Application{
+		DynamicPluginsRegistry: &bs.DynamicPluginsRegistry{Npmrc: &bs.ObjectKeyRef{Name: "npmrc"}},
+	}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.dynamicPluginsRegistry.npmrc.key"}, causeFields(err))
+
+	_, err = v.ValidateCreate(ctx, webhookBackstage(&bs.Application{
+		DynamicPluginsRegistry: &bs.DynamicPluginsRegistry{Npmrc: &bs.ObjectKeyRef{Name: "npmrc", Key: "config"}},
+	}))
+	assert.NoError(t, err)
+}
+
+func TestValidateWebhookRouteTLS(t *testing.T) {
+	v := BackstageValidator{Client: NewMockClient()}
+
+	_, err := v.ValidateCreate(context.TODO(), webhookBackstage(&bs.Application{
+		Route: &bs.Route{TLS: &bs.TLS{Certificate: "cert", ExternalCertificateSecretName: "secret"}},
+	}))
+	assert.True(t, errors.IsInvalid(err))
+	assert.Equal(t, []string{"spec.application.route.tls.externalCertificateSecretName"}, causeFields(err))
+}
@@ -0,0 +1,219 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// max number of drifted objects listed in the Event message
+const maxDriftEventObjects = 5
+
+// metadata fields maintained by the API server, not a subject of drift
+var serverMetadataFields = []string{"managedFields", "resourceVersion", "generation", "creationTimestamp", "uid", "selfLink"}
+
+// detectDrift compares the desired runtime objects with the live ones without updating them.
+// The desired state is calculated by server-side apply dry run, so the defaults and the fields managed by others
+// are taken into account. Returns the drift and the objects which do not exist yet (to be created)
+func (r *BackstageReconciler) detectDrift(ctx context.Context, objects []model.RuntimeObject) ([]bs.ObjectDrift, []model.RuntimeObject, error) {
+
+	lg := log.FromContext(ctx)
+
+	var drift []bs.ObjectDrift
+	var missing []model.RuntimeObject
+	for _, obj := range objects {
+
+		live := obj.EmptyObject()
+		if err := r.Get(ctx, types.NamespacedName{Name: obj.Object().GetName(), Namespace: obj.Object().GetNamespace()}, live); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, nil, fmt.Errorf("failed to get object: %w", err)
+			}
+			missing = append(missing, obj)
+			continue
+		}
+
+		//if DBSecret - nothing to compare, it is not for update
+		if _, ok := obj.(*model.DbSecret); ok {
+			continue
+		}
+
+		desired := obj.Object().DeepCopyObject().(client.Object)
+		if err := r.setApplyConfiguration(desired); err != nil {
+			return nil, nil, err
+		}
+		if err := r.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership, client.DryRunAll); err != nil {
+			return nil, nil, fmt.Errorf("failed to dry run apply object %s %s: %w", objDispName(obj), desired.GetName(), err)
+		}
+
+		fields, err := driftFields(live, desired)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to compare object %s %s: %w", objDispName(obj), desired.GetName(), err)
+		}
+		if len(fields) > 0 {
+			lg.V(1).Info("object drifted ", objDispName(obj), desired.GetName(), "fields", fields)
+			drift = append(drift, bs.ObjectDrift{
+				Kind:   desired.GetObjectKind().GroupVersionKind().Kind,
+				Name:   desired.GetName(),
+				Fields: fields,
+			})
+		}
+	}
+	return drift, missing, nil
+}
+
+// detectObsolete returns the existing runtime objects which are not desired anymore (see objectsToClean) as the drift to be deleted.
+// Stale mirrors are not runtime objects, so not reported
+func (r *BackstageReconciler) detectObsolete(ctx context.Context, backstage bs.Backstage, bsModel *model.BackstageModel) ([]bs.ObjectDrift, error) {
+
+	toClean, err := r.objectsToClean(ctx, backstage, bsModel)
+	if err != nil {
+		return nil, err
+	}
+	var runtimeObjects []client.Object
+	for _, obj := range toClean {
+		if !isMirror(obj) {
+			runtimeObjects = append(runtimeObjects, obj)
+		}
+	}
+	refs, err := r.existingObjects(ctx, runtimeObjects)
+	if err != nil {
+		return nil, err
+	}
+	var obsolete []bs.ObjectDrift
+	for _, ref := range refs {
+		obsolete = append(obsolete, bs.ObjectDrift{Kind: ref.Kind, Name: ref.Name, Delete: true})
+	}
+	return obsolete, nil
+}
+
+// reportDrift sets the drift to the Backstage status and records the Event if the drift has changed
+func (r *BackstageReconciler) reportDrift(backstage *bs.Backstage, drift []bs.ObjectDrift) {
+
+	if reflect.DeepEqual(backstage.Status.Drift, drift) {
+		return
+	}
+	backstage.Status.Drift = drift
+
+	if len(drift) == 0 {
+		r.recordEvent(backstage, corev1.EventTypeNormal, EventReasonDriftResolved, "runtime objects match the desired state")
+		return
+	}
+	var objects []string
+	for i, d := range drift {
+		if i == maxDriftEventObjects {
+			objects = append(objects, fmt.Sprintf("and %d more", len(drift)-maxDriftEventObjects))
+			break
+		}
+		if d.Delete {
+			objects = append(objects, fmt.Sprintf("%s %s (not desired anymore)", d.Kind, d.Name))
+		} else {
+			objects = append(objects, fmt.Sprintf("%s %s (%s)", d.Kind, d.Name, strings.Join(d.Fields, ", ")))
+		}
+	}
+	r.recordEvent(backstage, corev1.EventTypeWarning, EventReasonDriftDetected,
+		"runtime objects differ from the desired state: %s", strings.Join(objects, "; "))
+}
+
+// driftFields returns sorted paths of the fields which differ between live and desired objects,
+// ignoring the status and the metadata maintained by the API server
+func driftFields(live, desired client.Object) ([]string, error) {
+	liveContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	desiredContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, err
+	}
+	for _, content := range []map[string]interface{}{liveContent, desiredContent} {
+		delete(content, "status")
+		delete(content, "apiVersion")
+		delete(content, "kind")
+		if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+			for _, f := range serverMetadataFields {
+				delete(metadata, f)
+			}
+		}
+	}
+
+	var fields []string
+	diffValues("", liveContent, desiredContent, &fields)
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// diffValues appends the paths of the differences between live and desired values to fields.
+// Lists of different length are reported as a whole
+func diffValues(path string, live, desired interface{}, fields *[]string) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			*fields = append(*fields, path)
+			return
+		}
+		for key := range unionKeys(l, d) {
+			diffValues(joinPath(path, key), l[key], d[key], fields)
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			*fields = append(*fields, path)
+			return
+		}
+		for i := range d {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), l[i], d[i], fields)
+		}
+	default:
+		if !reflect.DeepEqual(live, desired) {
+			*fields = append(*fields, path)
+		}
+	}
+}
+
+func unionKeys(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%s[%s]", path, key)
+	}
+	return path + "." + key
+}
@@ -0,0 +1,118 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+func TestDriftFields(t *testing.T) {
+
+	live := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "backstage-bs1", ResourceVersion: "1", Generation: 1,
+			Annotations: map[string]string{"app.kubernetes.io/edited": "true"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(int32(1)),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "backstage-backend", Image: "quay.io/custom:latest"}},
+			}},
+		},
+		Status: appsv1.DeploymentStatus{AvailableReplicas: 1},
+	}
+
+	desired := live.DeepCopy()
+	desired.ResourceVersion = "2"
+	desired.Generation = 2
+	desired.Status = appsv1.DeploymentStatus{}
+	fields, err := driftFields(live, desired)
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	desired.Spec.Template.Spec.Containers[0].Image = "quay.io/rhdh/rhdh-hub-rhel9:latest"
+	desired.Annotations = nil
+	fields, err = driftFields(live, desired)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata.annotations", "spec.template.spec.containers[0].image"}, fields)
+
+	desired = live.DeepCopy()
+	desired.Annotations["app.kubernetes.io/edited"] = "false"
+	desired.Spec.Template.Spec.Containers = append(desired.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar"})
+	fields, err = driftFields(live, desired)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata.annotations[app.kubernetes.io/edited]", "spec.template.spec.containers"}, fields)
+}
+
+func TestReportDrift(t *testing.T) {
+
+	recorder := record.NewFakeRecorder(10)
+	rc := BackstageReconciler{Recorder: recorder}
+	backstage := &bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+
+	drift := []bs.ObjectDrift{{Kind: "Deployment", Name: "backstage-bs1", Fields: []string{"spec.replicas"}}}
+	rc.reportDrift(backstage, drift)
+	assert.Equal(t, drift, backstage.Status.Drift)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "DriftDetected")
+
+	// not changed, no event
+	rc.reportDrift(backstage, []bs.ObjectDrift{{Kind: "Deployment", Name: "backstage-bs1", Fields: []string{"spec.replicas"}}})
+	assert.Empty(t, recorder.Events)
+
+	rc.reportDrift(backstage, nil)
+	assert.Nil(t, backstage.Status.Drift)
+	assert.Contains(t, <-recorder.Events, "DriftResolved")
+}
+
+func TestObsoleteObjectsReportedNotDeleted(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme(), Recorder: record.NewFakeRecorder(10)}
+
+	// the Ingress created before, no longer in the spec
+	ingress := networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: model.IngressName("bs1"), Namespace: "ns1"}}
+	assert.NoError(t, rc.Create(ctx, &ingress))
+
+	bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), false, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+
+	obsolete, err := rc.detectObsolete(ctx, backstage, bsModel)
+	assert.NoError(t, err)
+	assert.Equal(t, []bs.ObjectDrift{{Kind: "Ingress", Name: model.IngressName("bs1"), Delete: true}}, obsolete)
+
+	// drift report mode, not deleted
+	assert.NoError(t, rc.cleanObjects(ctx, &backstage, bsModel, false))
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Name: model.IngressName("bs1"), Namespace: "ns1"}, &ingress))
+
+	assert.NoError(t, rc.cleanObjects(ctx, &backstage, bsModel, true))
+	assert.Error(t, rc.Get(ctx, types.NamespacedName{Name: model.IngressName("bs1"), Namespace: "ns1"}, &ingress))
+}
@@ -0,0 +1,118 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Event reasons
+const (
+	EventReasonCreated          = "Created"
+	EventReasonUpdated          = "Updated"
+	EventReasonDeleted          = "Deleted"
+	EventReasonRestarted        = "ConfigChanged"
+	EventReasonDbSecret         = "DbSecretGenerated"
+	EventReasonPreprocessFailed = "PreprocessFailed"
+	EventReasonValidationFailed = "ValidationFailed"
+	EventReasonApplyFailed      = "ApplyFailed"
+	EventReasonApplyConflict    = "ApplyConflict"
+	EventReasonCleanupFailed    = "CleanupFailed"
+	EventReasonPlanFailed       = "PlanFailed"
+	EventReasonDriftFailed      = "DriftDetectionFailed"
+	EventReasonDriftDetected    = "DriftDetected"
+	EventReasonDriftResolved    = "DriftResolved"
+)
+
+// the same event is not emitted again within this interval
+const eventDedupInterval = 5 * time.Minute
+
+// dedupRecorder is record.EventRecorder which drops the events repeated (same object, type, reason and message)
+// within the interval. So, reconciliation failing again and again does not flood the API with the same Events.
+type dedupRecorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	emitted map[string]time.Time
+}
+
+// NewEventRecorder wraps the recorder to drop the same events repeated within a few minutes
+func NewEventRecorder(recorder record.EventRecorder) record.EventRecorder {
+	return &dedupRecorder{
+		recorder: recorder,
+		interval: eventDedupInterval,
+		now:      time.Now,
+		emitted:  map[string]time.Time{},
+	}
+}
+
+func (d *dedupRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if d.allow(object, eventtype, reason, message) {
+		d.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (d *dedupRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	d.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (d *dedupRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if d.allow(object, eventtype, reason, message) {
+		d.recorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// allow returns true if the event was not emitted within the interval, and remembers it
+func (d *dedupRecorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	key := fmt.Sprintf("%s/%s/%s/%s", eventtype, reason, message, objectKey(object))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for k, t := range d.emitted {
+		if now.Sub(t) >= d.interval {
+			delete(d.emitted, k)
+		}
+	}
+	if _, ok := d.emitted[key]; ok {
+		return false
+	}
+	d.emitted[key] = now
+	return true
+}
+
+func objectKey(object runtime.Object) string {
+	if obj, ok := object.(client.Object); ok {
+		return fmt.Sprintf("%s/%s/%s", obj.GetNamespace(), obj.GetName(), obj.GetUID())
+	}
+	return fmt.Sprintf("%p", object)
+}
+
+// recordEvent records the event on the Backstage object, if the recorder is configured
+func (r *BackstageReconciler) recordEvent(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
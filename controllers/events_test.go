@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDedupRecorder(t *testing.T) {
+
+	fake := record.NewFakeRecorder(10)
+	recorder := NewEventRecorder(fake).(*dedupRecorder)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+
+	bs1 := &bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+	bs2 := &bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs2", Namespace: "ns1"}}
+
+	recorder.Eventf(bs1, corev1.EventTypeWarning, EventReasonPreprocessFailed, "failed %s", "cm1")
+	recorder.Eventf(bs1, corev1.EventTypeWarning, EventReasonPreprocessFailed, "failed %s", "cm1")
+	assert.Len(t, fake.Events, 1)
+
+	// different message or object
+	recorder.Eventf(bs1, corev1.EventTypeWarning, EventReasonPreprocessFailed, "failed %s", "cm2")
+	recorder.Eventf(bs2, corev1.EventTypeWarning, EventReasonPreprocessFailed, "failed %s", "cm1")
+	assert.Len(t, fake.Events, 3)
+
+	// interval passed
+	now = now.Add(eventDedupInterval)
+	recorder.Eventf(bs1, corev1.EventTypeWarning, EventReasonPreprocessFailed, "failed %s", "cm1")
+	assert.Len(t, fake.Events, 4)
+	assert.Len(t, recorder.emitted, 1)
+}
+
+func TestApplyObjectsEvents(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+	fake := record.NewFakeRecorder(100)
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme(), Recorder: fake}
+
+	bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+	_, err = rc.applyObjects(ctx, &backstage, bsModel.RuntimeObjects)
+	assert.NoError(t, err)
+
+	var events []string
+	for len(fake.Events) > 0 {
+		events = append(events, <-fake.Events)
+	}
+	assert.Contains(t, events, "Normal DbSecretGenerated generated database Secret "+model.DbSecretDefaultName("bs1"))
+	assert.Contains(t, events, "Normal Created created Deployment "+model.DeploymentName("bs1"))
+	assert.Contains(t, events, "Normal Created created StatefulSet "+model.DbStatefulSetName("bs1"))
+}
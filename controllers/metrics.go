@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Reconciliation phases measured by reconcilePhaseDuration
+const (
+	phasePreprocess = "preprocess"
+	phaseModelInit  = "model_init"
+	phaseApply      = "apply"
+	phaseCleanup    = "cleanup"
+)
+
+var (
+	reconcilePhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "backstage_reconcile_phase_duration_seconds",
+		Help:    "Duration of the Backstage reconciliation phases (preprocess, model_init, apply, cleanup)",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"phase"})
+
+	reconcileFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backstage_reconcile_failures_total",
+		Help: "Number of failed Backstage reconciliations by reason",
+	}, []string{"namespace", "name", "reason"})
+
+	extConfigHashChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backstage_ext_config_hash_changes_total",
+		Help: "Number of external configuration changes restarting the Backstage Pods",
+	}, []string{"namespace", "name"})
+
+	recreatedObjects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "backstage_recreated_objects_total",
+		Help: "Number of runtime objects deleted to be recreated, as they could not be updated",
+	}, []string{"namespace", "name", "kind"})
+
+	instances = newInstancesCollector()
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcilePhaseDuration, reconcileFailures, extConfigHashChanges, recreatedObjects, instances)
+}
+
+// observePhase records the duration of the reconciliation phase started at start
+func observePhase(phase string, start time.Time) {
+	reconcilePhaseDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// removeInstanceMetrics deletes the series of the Backstage instance, which is gone
+func removeInstanceMetrics(instance types.NamespacedName) {
+	labels := prometheus.Labels{"namespace": instance.Namespace, "name": instance.Name}
+	reconcileFailures.DeletePartialMatch(labels)
+	extConfigHashChanges.DeletePartialMatch(labels)
+	recreatedObjects.DeletePartialMatch(labels)
+	instances.remove(instance)
+}
+
+// instancesCollector exposes the readiness of every managed Backstage instance
+// as well as the number of instances by readiness
+type instancesCollector struct {
+	readyDesc     *prometheus.Desc
+	instancesDesc *prometheus.Desc
+
+	mu    sync.Mutex
+	ready map[types.NamespacedName]bool
+}
+
+func newInstancesCollector() *instancesCollector {
+	return &instancesCollector{
+		readyDesc: prometheus.NewDesc("backstage_instance_ready",
+			"Whether the Backstage instance is ready (1) or not (0)", []string{"namespace", "name"}, nil),
+		instancesDesc: prometheus.NewDesc("backstage_instances",
+			"Number of managed Backstage instances by readiness", []string{"ready"}, nil),
+		ready: map[types.NamespacedName]bool{},
+	}
+}
+
+func (c *instancesCollector) set(instance types.NamespacedName, ready bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready[instance] = ready
+}
+
+func (c *instancesCollector) remove(instance types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ready, instance)
+}
+
+// Describe implements prometheus.Collector
+func (c *instancesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.readyDesc
+	ch <- c.instancesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *instancesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := map[bool]float64{true: 0, false: 0}
+	for instance, ready := range c.ready {
+		value := 0.0
+		if ready {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.readyDesc, prometheus.GaugeValue, value, instance.Namespace, instance.Name)
+		counts[ready]++
+	}
+	for ready, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.instancesDesc, prometheus.GaugeValue, count, strconv.FormatBool(ready))
+	}
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestInstancesCollector(t *testing.T) {
+
+	c := newInstancesCollector()
+	c.set(types.NamespacedName{Namespace: "ns1", Name: "bs1"}, true)
+	c.set(types.NamespacedName{Namespace: "ns1", Name: "bs2"}, false)
+	c.set(types.NamespacedName{Namespace: "ns2", Name: "bs1"}, true)
+
+	expected := `
+# HELP backstage_instance_ready Whether the Backstage instance is ready (1) or not (0)
+# TYPE backstage_instance_ready gauge
+backstage_instance_ready{name="bs1",namespace="ns1"} 1
+backstage_instance_ready{name="bs1",namespace="ns2"} 1
+backstage_instance_ready{name="bs2",namespace="ns1"} 0
+# HELP backstage_instances Number of managed Backstage instances by readiness
+# TYPE backstage_instances gauge
+backstage_instances{ready="false"} 1
+backstage_instances{ready="true"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+
+	c.remove(types.NamespacedName{Namespace: "ns1", Name: "bs2"})
+	assert.Equal(t, 2, testutil.CollectAndCount(c, "backstage_instance_ready"))
+}
+
+func TestReconcileFailuresMetric(t *testing.T) {
+
+	rc := BackstageReconciler{}
+	backstage := &bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "metrics-bs", Namespace: "ns1"}}
+
+	before := testutil.ToFloat64(reconcileFailures.WithLabelValues("ns1", "metrics-bs", EventReasonPreprocessFailed))
+	assert.Error(t, rc.errorAndStatus(backstage, EventReasonPreprocessFailed, "failed to preprocess backstage spec", fmt.Errorf("not found")))
+	assert.Equal(t, before+1, testutil.ToFloat64(reconcileFailures.WithLabelValues("ns1", "metrics-bs", EventReasonPreprocessFailed)))
+}
+
+func TestInstanceMetricsOnFailureAndRemoval(t *testing.T) {
+
+	rc := BackstageReconciler{}
+	instance := types.NamespacedName{Namespace: "ns1", Name: "metrics-gone"}
+	backstage := &bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: instance.Name, Namespace: instance.Namespace}}
+
+	instances.set(instance, true)
+	extConfigHashChanges.WithLabelValues(instance.Namespace, instance.Name).Inc()
+
+	// failing instance is not ready anymore
+	assert.Error(t, rc.errorAndStatus(backstage, EventReasonApplyFailed, "failed to apply backstage objects", fmt.Errorf("forbidden")))
+	instances.mu.Lock()
+	assert.False(t, instances.ready[instance])
+	instances.mu.Unlock()
+
+	removeInstanceMetrics(instance)
+	instances.mu.Lock()
+	_, found := instances.ready[instance]
+	instances.mu.Unlock()
+	assert.False(t, found)
+	assert.Equal(t, 0, reconcileFailures.DeletePartialMatch(map[string]string{"namespace": instance.Namespace, "name": instance.Name}))
+	assert.Equal(t, 0, extConfigHashChanges.DeletePartialMatch(map[string]string{"namespace": instance.Namespace, "name": instance.Name}))
+}
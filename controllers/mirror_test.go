@@ -0,0 +1,252 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCrossNamespaceReference(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+
+	backstage := bs.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: bs.BackstageSpec{
+			Application: &bs.Application{
+				AppConfig: &bs.AppConfig{
+					ConfigMaps: []bs.ObjectKeyRef{{Name: "branding", Namespace: "shared"}},
+				},
+				ExtraEnvs: &bs.ExtraEnvs{
+					Secrets: []bs.ObjectKeyRef{{Name: "auth", Namespace: "shared"}},
+				},
+			},
+		},
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "branding", Namespace: "shared"},
+		Data: map[string]string{"branding.yaml": "app:\n  title: Org"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "shared"},
+		Data: map[string][]byte{"TOKEN": []byte("t1")}}
+
+	rc := BackstageReconciler{
+		Client: fake.NewClientBuilder().WithScheme(renderTestScheme()).WithObjects(cm, secret).Build(),
+		Scheme: renderTestScheme(),
+	}
+
+	// not granted
+	_, err := rc.preprocessSpec(ctx, backstage)
+	assert.ErrorContains(t, err, "ConfigMap shared/branding can not be referred from namespace ns1")
+
+	// the Secret is granted for another namespace only
+	grant := &bs.BackstageReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "backstage", Namespace: "shared"},
+		Spec: bs.BackstageReferenceGrantSpec{
+			From: []bs.ReferenceGrantFrom{{Namespace: "ns1"}},
+			To:   []bs.ReferenceGrantTo{{Kind: "ConfigMap"}, {Kind: "Secret", Name: "other"}},
+		},
+	}
+	assert.NoError(t, rc.Create(ctx, grant))
+	_, err = rc.preprocessSpec(ctx, backstage)
+	assert.ErrorContains(t, err, "Secret shared/auth can not be referred from namespace ns1")
+
+	grant.Spec.To[1].Name = "auth"
+	assert.NoError(t, rc.Update(ctx, grant))
+	extConf, err := rc.preprocessSpec(ctx, backstage)
+	assert.NoError(t, err)
+
+	cmMirror := model.MirrorName("bs1", "shared", "branding")
+	secretMirror := model.MirrorName("bs1", "shared", "auth")
+	assert.Len(t, extConf.Mirrors, 2)
+	assert.Equal(t, cmMirror, extConf.AppConfigs["shared/branding"].Name)
+	assert.Equal(t, "ns1", extConf.AppConfigs["shared/branding"].Namespace)
+	assert.Equal(t, "shared/branding", extConf.AppConfigs["shared/branding"].Annotations[model.MirroredFromAnnotation])
+	assert.Equal(t, cm.Data, extConf.AppConfigs["shared/branding"].Data)
+	assert.Equal(t, secretMirror, extConf.ExtraEnvSecrets["shared/auth"].Name)
+
+	// the mirrors are applied along with the other runtime objects, used by the Deployment
+	bsModel, err := model.InitObjects(ctx, backstage, extConf, true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+	var deployment *appsv1.Deployment
+	mirrors := map[string]bool{}
+	for _, obj := range bsModel.RuntimeObjects {
+		if _, ok := obj.(*model.MirroredObject); ok {
+			assert.Equal(t, "ns1", obj.Object().GetNamespace())
+			assert.Equal(t, "bs1", obj.Object().GetLabels()[utils.KubeInstanceLabel])
+			mirrors[obj.Object().GetName()] = true
+		}
+		if d, ok := obj.Object().(*appsv1.Deployment); ok {
+			deployment = d
+		}
+	}
+	assert.Equal(t, map[string]bool{cmMirror: true, secretMirror: true}, mirrors)
+	assert.NotNil(t, deployment)
+	var cmVolume *corev1.ConfigMapVolumeSource
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		if v.Name == utils.GenerateVolumeNameFromCmOrSecret(cmMirror) {
+			cmVolume = v.ConfigMap
+		}
+	}
+	if assert.NotNil(t, cmVolume) {
+		assert.Equal(t, cmMirror, cmVolume.Name)
+	}
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].EnvFrom, corev1.EnvFromSource{
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secretMirror}},
+	})
+
+	// the changes of the source objects are tracked
+	assert.Contains(t, externalConfigRefs(&backstage), "ConfigMap/shared/branding")
+	assert.Contains(t, externalConfigRefs(&backstage), "Secret/shared/auth")
+	assert.True(t, backstage.IsRestartOnChange("ConfigMap", "shared", "branding", true))
+	assert.False(t, backstage.IsRestartOnChange("ConfigMap", "ns1", "branding", true))
+	assert.True(t, refersNamespace(backstage, "shared"))
+	assert.False(t, refersNamespace(backstage, "ns1"))
+
+	// the mirror no longer referred is cleaned
+	for _, obj := range bsModel.RuntimeObjects {
+		if _, ok := obj.(*model.MirroredObject); ok {
+			assert.NoError(t, rc.Create(ctx, obj.Object()))
+		}
+	}
+	backstage.Spec.Application.ExtraEnvs = nil
+	extConf, err = rc.preprocessSpec(ctx, backstage)
+	assert.NoError(t, err)
+	bsModel, err = model.InitObjects(ctx, backstage, extConf, true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+	stale, err := rc.staleMirrors(ctx, backstage, bsModel)
+	assert.NoError(t, err)
+	assert.Len(t, stale, 1)
+	assert.Equal(t, secretMirror, stale[0].GetName())
+	assert.IsType(t, &corev1.Secret{}, stale[0])
+}
+
+func TestHotReloadedCrossNamespaceChangeEnqueued(t *testing.T) {
+
+	ctx := context.TODO()
+
+	backstage := bs.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: bs.BackstageSpec{
+			Application: &bs.Application{
+				AppConfig: &bs.AppConfig{
+					HotReload:  true,
+					ConfigMaps: []bs.ObjectKeyRef{{Name: "branding", Namespace: "shared"}, {Name: "local"}},
+				},
+			},
+		},
+	}
+	shared := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "branding", Namespace: "shared"},
+		Data: map[string]string{"branding.yaml": "app:\n  title: Org"}}
+	local := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "ns1"},
+		Data: map[string]string{"local.yaml": "app:\n  title: Local"}}
+	grant := &bs.BackstageReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "backstage", Namespace: "shared"},
+		Spec: bs.BackstageReferenceGrantSpec{
+			From: []bs.ReferenceGrantFrom{{Namespace: "ns1"}},
+			To:   []bs.ReferenceGrantTo{{Kind: "ConfigMap"}},
+		},
+	}
+	rc := BackstageReconciler{
+		Client: fake.NewClientBuilder().WithScheme(renderTestScheme()).WithObjects(shared, local, grant).Build(),
+		Scheme: renderTestScheme(),
+	}
+
+	// the Pods are deployed with the current config hash
+	extConf, err := rc.preprocessSpec(ctx, backstage)
+	assert.NoError(t, err)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: model.DeploymentName("bs1"), Namespace: "ns1"}}
+	deployment.Spec.Template.Annotations = map[string]string{model.ExtConfigHashAnnotation: extConf.GetHash()}
+	assert.NoError(t, rc.Create(ctx, deployment))
+
+	// hot reloaded, not hashed, so the hash is not changed
+	shared.Data["branding.yaml"] = "app:\n  title: Changed"
+	assert.NoError(t, rc.Update(ctx, shared))
+	local.Data["local.yaml"] = "app:\n  title: Changed"
+	assert.NoError(t, rc.Update(ctx, local))
+	extConf, err = rc.preprocessSpec(ctx, backstage)
+	assert.NoError(t, err)
+	assert.Equal(t, deployment.Spec.Template.Annotations[model.ExtConfigHashAnnotation], extConf.GetHash())
+
+	// but the mirror has to be updated
+	assert.True(t, rc.externalConfigChanged(ctx, "ConfigMap", shared, backstage))
+	// mounted directly, updated by kubelet
+	assert.False(t, rc.externalConfigChanged(ctx, "ConfigMap", local, backstage))
+}
+
+func TestRevokedGrantMirrorCleaned(t *testing.T) {
+
+	ctx := context.TODO()
+
+	backstage := &bs.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: bs.BackstageSpec{
+			Application: &bs.Application{
+				ExtraEnvs: &bs.ExtraEnvs{
+					Secrets: []bs.ObjectKeyRef{{Name: "auth", Namespace: "shared"}},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "shared"},
+		Data: map[string][]byte{"TOKEN": []byte("t1")}}
+	grant := &bs.BackstageReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "backstage", Namespace: "shared"},
+		Spec: bs.BackstageReferenceGrantSpec{
+			From: []bs.ReferenceGrantFrom{{Namespace: "ns1"}},
+			To:   []bs.ReferenceGrantTo{{Kind: "Secret"}},
+		},
+	}
+	// mirrored while the grant was in place, along with the mirror of another Backstage instance
+	mirror, err := model.Mirror(secret, *backstage)
+	assert.NoError(t, err)
+	mirror.GetLabels()[utils.KubeInstanceLabel] = "bs1"
+	other := mirror.DeepCopyObject().(*corev1.Secret)
+	other.Name = model.MirrorName("bs2", "shared", "auth")
+	other.Labels[utils.KubeInstanceLabel] = "bs2"
+
+	rc := BackstageReconciler{
+		Client: fake.NewClientBuilder().WithScheme(renderTestScheme()).WithStatusSubresource(&bs.Backstage{}).
+			WithObjects(backstage, secret, grant, mirror, other).Build(),
+		Scheme: renderTestScheme(),
+	}
+
+	// granted, the mirror is kept
+	assert.NoError(t, rc.cleanUngrantedMirrors(ctx, backstage))
+	assert.NoError(t, rc.Get(ctx, client.ObjectKeyFromObject(mirror), &corev1.Secret{}))
+
+	// revoked, the preprocessing fails, but the mirror is deleted
+	assert.NoError(t, rc.Delete(ctx, grant))
+	_, err = rc.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(backstage)})
+	assert.ErrorContains(t, err, "Secret shared/auth can not be referred from namespace ns1")
+	assert.True(t, errors.IsNotFound(rc.Get(ctx, client.ObjectKeyFromObject(mirror), &corev1.Secret{})))
+	// the mirror of another instance is left to its own reconciliation
+	assert.NoError(t, rc.Get(ctx, client.ObjectKeyFromObject(other), &corev1.Secret{}))
+}
@@ -23,8 +23,11 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -43,8 +46,9 @@ func NewMockClient() MockClient {
 }
 
 type NameKind struct {
-	Name string
-	Kind string
+	Namespace string
+	Name      string
+	Kind      string
 }
 
 func kind(obj runtime.Object) string {
@@ -58,7 +62,7 @@ func (m MockClient) Get(_ context.Context, key client.ObjectKey, obj client.Obje
 	if key.Name == "" {
 		return fmt.Errorf("get: name should not be empty")
 	}
-	uobj := m.objects[NameKind{Name: key.Name, Kind: kind(obj)}]
+	uobj := m.objects[NameKind{Namespace: key.Namespace, Name: key.Name, Kind: kind(obj)}]
 	if uobj == nil {
 		return errors.NewNotFound(schema.GroupResource{Group: "", Resource: kind(obj)}, key.Name)
 	}
@@ -69,15 +73,39 @@ func (m MockClient) Get(_ context.Context, key client.ObjectKey, obj client.Obje
 	return nil
 }
 
-func (m MockClient) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
-	panic(implementMe)
+// List returns all the objects of the list item kind, filtered by namespace and label selector (if any)
+func (m MockClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	itemKind := strings.TrimSuffix(kind(list), "List")
+
+	items := []json.RawMessage{}
+	for nk, uobj := range m.objects {
+		if nk.Kind != itemKind || (listOpts.Namespace != "" && nk.Namespace != listOpts.Namespace) {
+			continue
+		}
+		if listOpts.LabelSelector != nil {
+			objMeta := metav1.PartialObjectMetadata{}
+			if err := json.Unmarshal(uobj, &objMeta); err != nil {
+				return err
+			}
+			if !listOpts.LabelSelector.Matches(labels.Set(objMeta.Labels)) {
+				continue
+			}
+		}
+		items = append(items, uobj)
+	}
+	dat, err := json.Marshal(map[string]interface{}{"items": items})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(dat, list)
 }
 
 func (m MockClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
 	if obj.GetName() == "" {
 		return fmt.Errorf("update: object Name should not be empty")
 	}
-	uobj := m.objects[NameKind{Name: obj.GetName(), Kind: kind(obj)}]
+	uobj := m.objects[NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}]
 	if uobj != nil {
 		return errors.NewAlreadyExists(schema.GroupResource{Group: "", Resource: kind(obj)}, obj.GetName())
 	}
@@ -85,12 +113,17 @@ func (m MockClient) Create(_ context.Context, obj client.Object, _ ...client.Cre
 	if err != nil {
 		return err
 	}
-	m.objects[NameKind{Name: obj.GetName(), Kind: kind(obj)}] = dat
+	m.objects[NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}] = dat
 	return nil
 }
 
-func (m MockClient) Delete(_ context.Context, _ client.Object, _ ...client.DeleteOption) error {
-	panic(implementMe)
+func (m MockClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	key := NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}
+	if m.objects[key] == nil {
+		return errors.NewNotFound(schema.GroupResource{Group: "", Resource: kind(obj)}, obj.GetName())
+	}
+	delete(m.objects, key)
+	return nil
 }
 
 func (m MockClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
@@ -98,7 +131,7 @@ func (m MockClient) Update(_ context.Context, obj client.Object, _ ...client.Upd
 	if obj.GetName() == "" {
 		return fmt.Errorf("update: object Name should not be empty")
 	}
-	uobj := m.objects[NameKind{Name: obj.GetName(), Kind: kind(obj)}]
+	uobj := m.objects[NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}]
 	if uobj == nil {
 		return errors.NewNotFound(schema.GroupResource{Group: "", Resource: kind(obj)}, obj.GetName())
 	}
@@ -106,12 +139,27 @@ func (m MockClient) Update(_ context.Context, obj client.Object, _ ...client.Upd
 	if err != nil {
 		return err
 	}
-	m.objects[NameKind{Name: obj.GetName(), Kind: kind(obj)}] = dat
+	m.objects[NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}] = dat
 	return nil
 }
 
-func (m MockClient) Patch(_ context.Context, _ client.Object, _ client.Patch, _ ...client.PatchOption) error {
-	panic(implementMe)
+// Patch supports server-side apply only, which simply stores the object (unless dry run)
+func (m MockClient) Patch(_ context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		panic(implementMe)
+	}
+	if obj.GetName() == "" {
+		return fmt.Errorf("patch: object Name should not be empty")
+	}
+	if (&client.PatchOptions{}).ApplyOptions(opts).DryRun != nil {
+		return nil
+	}
+	dat, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	m.objects[NameKind{Namespace: obj.GetNamespace(), Name: obj.GetName(), Kind: kind(obj)}] = dat
+	return nil
 }
 
 func (m MockClient) DeleteAllOf(_ context.Context, _ client.Object, _ ...client.DeleteAllOfOption) error {
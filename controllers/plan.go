@@ -0,0 +1,105 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// planObjects calculates the changes the reconciliation would make to the runtime objects, without making them:
+// the objects to create, update (using server-side apply dry run, see detectDrift) and delete
+func (r *BackstageReconciler) planObjects(ctx context.Context, backstage bs.Backstage, bsModel *model.BackstageModel) (*bs.Plan, error) {
+
+	update, create, err := r.detectDrift(ctx, bsModel.RuntimeObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &bs.Plan{
+		ObservedGeneration: backstage.Generation,
+		Update:             update,
+	}
+	for _, obj := range create {
+		ref, err := r.runtimeObjectRef(obj.Object())
+		if err != nil {
+			return nil, err
+		}
+		plan.Create = append(plan.Create, ref)
+	}
+
+	toClean, err := r.objectsToClean(ctx, backstage, bsModel)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Delete, err = r.existingObjects(ctx, toClean); err != nil {
+		return nil, err
+	}
+
+	plan.PodRestart = podRestart(update, model.DeploymentName(backstage.Name))
+
+	return plan, nil
+}
+
+// existingObjects returns the references to the objects which exist on the cluster
+func (r *BackstageReconciler) existingObjects(ctx context.Context, objects []client.Object) ([]bs.RuntimeObjectRef, error) {
+	var refs []bs.RuntimeObjectRef
+	for _, obj := range objects {
+		if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get object: %w", err)
+		}
+		ref, err := r.runtimeObjectRef(obj)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func (r *BackstageReconciler) runtimeObjectRef(obj client.Object) (bs.RuntimeObjectRef, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return bs.RuntimeObjectRef{}, fmt.Errorf("failed to get GroupVersionKind: %w", err)
+	}
+	return bs.RuntimeObjectRef{Kind: gvk.Kind, Name: obj.GetName()}, nil
+}
+
+// podRestart returns true if the update changes the Pod template of the Backstage Deployment
+func podRestart(update []bs.ObjectDrift, deploymentName string) bool {
+	for _, u := range update {
+		if u.Kind != "Deployment" || u.Name != deploymentName {
+			continue
+		}
+		for _, f := range u.Fields {
+			if f == "spec.template" || strings.HasPrefix(f, "spec.template.") {
+				return true
+			}
+		}
+	}
+	return false
+}
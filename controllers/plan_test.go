@@ -0,0 +1,85 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestPlanObjects(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	ctx := context.TODO()
+	backstage := bs.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1", Generation: 1}}
+	rc := BackstageReconciler{Client: NewMockClient(), Scheme: renderTestScheme()}
+
+	bsModel, err := model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+
+	// nothing deployed yet
+	plan, err := rc.planObjects(ctx, backstage, bsModel)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), plan.ObservedGeneration)
+	assert.Contains(t, plan.Create, bs.RuntimeObjectRef{Kind: "Deployment", Name: model.DeploymentName("bs1")})
+	assert.Contains(t, plan.Create, bs.RuntimeObjectRef{Kind: "StatefulSet", Name: model.DbStatefulSetName("bs1")})
+	assert.Empty(t, plan.Update)
+	assert.Empty(t, plan.Delete)
+	assert.False(t, plan.PodRestart)
+
+	_, err = rc.applyObjects(ctx, &backstage, bsModel.RuntimeObjects)
+	assert.NoError(t, err)
+
+	// no changes
+	bsModel, err = model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+	plan, err = rc.planObjects(ctx, backstage, bsModel)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Create)
+	assert.Empty(t, plan.Update)
+
+	// change the image and disable local database
+	backstage.Generation = 2
+	backstage.Spec.Application = &bs.Application{Image: ptr.To("quay.io/my/backstage:latest")}
+	backstage.Spec.Database = &bs.Database{EnableLocalDb: ptr.To(false)}
+	bsModel, err = model.InitObjects(ctx, backstage, model.NewExternalConfig(), true, model.Platform{}, rc.Scheme)
+	assert.NoError(t, err)
+	plan, err = rc.planObjects(ctx, backstage, bsModel)
+	assert.NoError(t, err)
+
+	assert.Empty(t, plan.Create)
+	assert.True(t, plan.PodRestart)
+	assert.Contains(t, plan.Delete, bs.RuntimeObjectRef{Kind: "StatefulSet", Name: model.DbStatefulSetName("bs1")})
+	assert.Contains(t, plan.Delete, bs.RuntimeObjectRef{Kind: "Secret", Name: model.DbSecretDefaultName("bs1")})
+	var deploymentUpdate *bs.ObjectDrift
+	for i := range plan.Update {
+		if plan.Update[i].Kind == "Deployment" {
+			deploymentUpdate = &plan.Update[i]
+		}
+	}
+	if assert.NotNil(t, deploymentUpdate) {
+		assert.Contains(t, deploymentUpdate.Fields, "spec.template.spec.containers[0].image")
+	}
+}
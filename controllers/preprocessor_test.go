@@ -16,15 +16,19 @@ package controller
 
 import (
 	"context"
-	"os"
 	"redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func updateConfigMap(t *testing.T) BackstageReconciler {
@@ -46,6 +50,7 @@ func updateConfigMap(t *testing.T) BackstageReconciler {
 
 	cm := corev1.ConfigMap{}
 	cm.Name = "cm1"
+	cm.Namespace = "ns1"
 
 	rc := BackstageReconciler{
 		Client: NewMockClient(),
@@ -57,8 +62,6 @@ func updateConfigMap(t *testing.T) BackstageReconciler {
 	extConf, err := rc.preprocessSpec(ctx, bs)
 	assert.NoError(t, err)
 
-	assert.NotNil(t, extConf.AppConfigs["cm1"].Labels)
-	assert.Equal(t, 1, len(extConf.AppConfigs["cm1"].Labels))
 	oldHash := extConf.GetHash()
 
 	// Update ConfigMap with new data
@@ -85,16 +88,380 @@ func TestExtConfigChanged(t *testing.T) {
 	rc := updateConfigMap(t)
 	err := rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "cm1"}, &cm)
 	assert.NoError(t, err)
-	// true : Backstage will be reconciled
-	assert.Equal(t, "true", cm.Labels[model.ExtConfigSyncLabel])
+	// the referenced object is not modified by the operator
+	assert.Empty(t, cm.Annotations)
+	assert.Empty(t, cm.Labels)
+}
+
+func TestLegacyMetadataRemoved(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				ExtraEnvs: &v1alpha2.ExtraEnvs{Secrets: []v1alpha2.ObjectKeyRef{{Name: "secret1"}}},
+			},
+		},
+	}
+	// stamped by the former versions of the operator
+	secret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "secret1",
+		Namespace:   "ns1",
+		Labels:      map[string]string{model.ExtConfigSyncLabel: "true", "app": "my-app"},
+		Annotations: map[string]string{model.BackstageNameAnnotation: "bs1"},
+	}}
+
+	rc := BackstageReconciler{Client: fake.NewClientBuilder().WithScheme(renderTestScheme()).WithObjects(&secret).Build()}
 
-	err = os.Setenv(AutoSyncEnvVar, "false")
+	// the preprocessing does not modify the object, it is also used in read-only modes (plan, drift report, render)
+	extConf, err := rc.preprocessSpec(ctx, bs)
 	assert.NoError(t, err)
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "secret1"}, &secret))
+	assert.Equal(t, "true", secret.Labels[model.ExtConfigSyncLabel])
+	assert.Len(t, extConf.LegacyStamped, 1)
 
-	rc = updateConfigMap(t)
-	err = rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "cm1"}, &cm)
+	// cleaned once applied
+	rc.removeLegacyMetadata(ctx, extConf.LegacyStamped)
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "secret1"}, &secret))
+	assert.Equal(t, map[string]string{"app": "my-app"}, secret.Labels)
+	assert.Empty(t, secret.Annotations)
+
+	extConf, err = rc.preprocessSpec(ctx, bs)
 	assert.NoError(t, err)
-	// false : Backstage will not be reconciled
-	assert.Equal(t, "false", cm.Labels[model.ExtConfigSyncLabel])
+	assert.Empty(t, extConf.LegacyStamped)
+}
+
+func TestRequestByReference(t *testing.T) {
+
+	for _, tc := range []struct {
+		name             string
+		restartOnChange  *bool
+		refRestartChange *bool
+		autoSync         string
+		expected         []string
+	}{
+		{name: "default", expected: []string{"bs1", "bs2"}},
+		{name: "disabled per instance", restartOnChange: ptr.To(false), expected: []string{"bs2"}},
+		{name: "disabled per reference", refRestartChange: ptr.To(false), expected: []string{"bs2"}},
+		{name: "enabled per reference", restartOnChange: ptr.To(false), refRestartChange: ptr.To(true), expected: []string{"bs1", "bs2"}},
+		{name: "disabled by env", autoSync: "false", expected: nil},
+		{name: "enabled per instance, disabled by env", restartOnChange: ptr.To(true), autoSync: "false", expected: []string{"bs1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			if tc.autoSync != "" {
+				t.Setenv(AutoSyncEnvVar, tc.autoSync)
+			}
+
+			// bs1 and bs2 share cm1, bs3 does not refer it
+			bs1 := v1alpha2.Backstage{
+				ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+				Spec: v1alpha2.BackstageSpec{
+					Application: &v1alpha2.Application{
+						AppConfig: &v1alpha2.AppConfig{
+							ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "cm1", RestartOnChange: tc.refRestartChange}},
+						},
+						RestartOnConfigChange: tc.restartOnChange,
+					},
+				},
+			}
+			bs2 := v1alpha2.Backstage{
+				ObjectMeta: metav1.ObjectMeta{Name: "bs2", Namespace: "ns1"},
+				Spec: v1alpha2.BackstageSpec{
+					Application: &v1alpha2.Application{
+						ExtraEnvs: &v1alpha2.ExtraEnvs{ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "cm1"}}},
+					},
+				},
+			}
+			bs3 := v1alpha2.Backstage{
+				ObjectMeta: metav1.ObjectMeta{Name: "bs3", Namespace: "ns1"},
+				Spec: v1alpha2.BackstageSpec{
+					Application: &v1alpha2.Application{
+						ExtraEnvs: &v1alpha2.ExtraEnvs{ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "cm2"}}},
+					},
+				},
+			}
+			cm1 := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns1"}}
+			cm2 := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm2", Namespace: "ns1"}}
+
+			rc := BackstageReconciler{
+				Client: fake.NewClientBuilder().WithScheme(renderTestScheme()).
+					WithIndex(&v1alpha2.Backstage{}, externalConfigRefIndex, externalConfigRefs).
+					WithObjects(&bs1, &bs2, &bs3, &cm1, &cm2).Build(),
+			}
+
+			for _, backstage := range []v1alpha2.Backstage{bs1, bs2, bs3} {
+				extConf, err := rc.preprocessSpec(ctx, backstage)
+				assert.NoError(t, err)
+				deploy := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: model.DeploymentName(backstage.Name), Namespace: "ns1"}}
+				deploy.Spec.Template.Annotations = map[string]string{model.ExtConfigHashAnnotation: extConf.GetHash()}
+				assert.NoError(t, rc.Create(ctx, &deploy))
+			}
 
+			// nothing changed
+			assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "cm1"}, &cm1))
+			assert.Empty(t, rc.requestByReference(ctx, "ConfigMap", &cm1))
+
+			cm1.Data = map[string]string{"key": "value"}
+			assert.NoError(t, rc.Update(ctx, &cm1))
+			var names []string
+			for _, req := range rc.requestByReference(ctx, "ConfigMap", &cm1) {
+				names = append(names, req.Name)
+			}
+			assert.ElementsMatch(t, tc.expected, names)
+		})
+	}
+}
+
+func TestExtConfigMetadataChanged(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				ExtraEnvs: &v1alpha2.ExtraEnvs{
+					Secrets: []v1alpha2.ObjectKeyRef{{Name: "secret1"}},
+				},
+			},
+		},
+	}
+
+	secret := corev1.Secret{}
+	secret.Name = "secret1"
+	secret.Namespace = "ns1"
+	secret.Data = map[string][]byte{"TOKEN": []byte("t1")}
+
+	rc := BackstageReconciler{Client: NewMockClient()}
+	assert.NoError(t, rc.Create(ctx, &secret))
+
+	// the first reconcile
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	oldHash := extConf.GetHash()
+
+	// re-label and annotate the secret, as e.g. secret-rotation controller does
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "secret1"}, &secret))
+	secret.Labels = map[string]string{"rotated-at": "20240101"}
+	secret.Annotations = map[string]string{"rotation": "nightly"}
+	secret.ResourceVersion = "2"
+	assert.NoError(t, rc.Update(ctx, &secret))
+
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	// pods are not restarted
+	assert.Equal(t, oldHash, extConf.GetHash())
+
+	secret.Data["TOKEN"] = []byte("t2")
+	assert.NoError(t, rc.Update(ctx, &secret))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+}
+
+func TestHotReloadAppConfigNotHashed(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				AppConfig: &v1alpha2.AppConfig{
+					HotReload:  true,
+					ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "app-config1"}},
+				},
+				ExtraEnvs: &v1alpha2.ExtraEnvs{
+					ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "envs1"}},
+				},
+			},
+		},
+	}
+
+	appConfig := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config1", Namespace: "ns1"}, Data: map[string]string{"app-config.yaml": "a"}}
+	envs := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "envs1", Namespace: "ns1"}, Data: map[string]string{"VAR": "v1"}}
+
+	rc := BackstageReconciler{Client: NewMockClient()}
+	assert.NoError(t, rc.Create(ctx, &appConfig))
+	assert.NoError(t, rc.Create(ctx, &envs))
+
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	oldHash := extConf.GetHash()
+
+	// app-config change is hot reloaded
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "app-config1"}, &appConfig))
+	appConfig.Data["app-config.yaml"] = "a2"
+	assert.NoError(t, rc.Update(ctx, &appConfig))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.Equal(t, oldHash, extConf.GetHash())
+
+	// env change restarts the Pods
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "envs1"}, &envs))
+	envs.Data["VAR"] = "v2"
+	assert.NoError(t, rc.Update(ctx, &envs))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+}
+
+func TestNotRestartingConfigNotHashed(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				ExtraEnvs: &v1alpha2.ExtraEnvs{
+					ConfigMaps: []v1alpha2.ObjectKeyRef{{Name: "envs1", RestartOnChange: ptr.To(false)}, {Name: "envs2"}},
+				},
+			},
+		},
+	}
+
+	envs1 := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "envs1", Namespace: "ns1"}, Data: map[string]string{"VAR1": "v1"}}
+	envs2 := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "envs2", Namespace: "ns1"}, Data: map[string]string{"VAR2": "v1"}}
+
+	rc := BackstageReconciler{Client: NewMockClient()}
+	assert.NoError(t, rc.Create(ctx, &envs1))
+	assert.NoError(t, rc.Create(ctx, &envs2))
+
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	oldHash := extConf.GetHash()
+
+	// the change is picked up on the next restart only
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "envs1"}, &envs1))
+	envs1.Data["VAR1"] = "v2"
+	assert.NoError(t, rc.Update(ctx, &envs1))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.Equal(t, oldHash, extConf.GetHash())
+
+	// restarts the Pods
+	assert.NoError(t, rc.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "envs2"}, &envs2))
+	envs2.Data["VAR2"] = "v2"
+	assert.NoError(t, rc.Update(ctx, &envs2))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+}
+
+func TestInlineAppConfigHashed(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				AppConfig: &v1alpha2.AppConfig{
+					Inline: &apiextensionsv1.JSON{Raw: []byte(`{"app":{"title":"one"}}`)},
+				},
+			},
+		},
+	}
+	rc := BackstageReconciler{Client: NewMockClient()}
+
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	oldHash := extConf.GetHash()
+
+	bs.Spec.Application.AppConfig.Inline = &apiextensionsv1.JSON{Raw: []byte(`{"app":{"title":"two"}}`)}
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+
+	// hot reloaded, not hashed
+	bs.Spec.Application.AppConfig.HotReload = true
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	empty := model.NewExternalConfig()
+	assert.Equal(t, empty.GetHash(), extConf.GetHash())
+}
+
+func TestDynamicPluginsRegistryHashed(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{
+				DynamicPluginsRegistry: &v1alpha2.DynamicPluginsRegistry{
+					Npmrc:    &v1alpha2.ObjectKeyRef{Name: "npmrc"},
+					CABundle: &v1alpha2.ObjectKeyRef{Name: "ca"},
+				},
+			},
+		},
+	}
+	rc := BackstageReconciler{Client: NewMockClient()}
+
+	npmrc := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "npmrc", Namespace: "ns1"},
+		Data: map[string][]byte{".npmrc": []byte("registry=https://npm.example.com")}}
+	ca := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "ns1"},
+		Data: map[string]string{"ca-bundle.crt": "cert"}}
+	assert.NoError(t, rc.Create(ctx, &npmrc))
+	assert.NoError(t, rc.Create(ctx, &ca))
+
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.Equal(t, "npmrc", extConf.DynamicPluginsNpmrc.Name)
+	assert.Equal(t, "ca", extConf.DynamicPluginsCABundle.Name)
+	oldHash := extConf.GetHash()
+
+	npmrc.Data[".npmrc"] = []byte("registry=https://npm2.example.com")
+	assert.NoError(t, rc.Update(ctx, &npmrc))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+	oldHash = extConf.GetHash()
+
+	ca.Data["ca-bundle.crt"] = "cert2"
+	assert.NoError(t, rc.Update(ctx, &ca))
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldHash, extConf.GetHash())
+
+	// missing object
+	bs.Spec.Application.DynamicPluginsRegistry.OCIAuth = &v1alpha2.ObjectKeyRef{Name: "absent"}
+	_, err = rc.preprocessSpec(ctx, bs)
+	assert.Error(t, err)
+}
+
+func TestDynamicPluginsCacheKeysOfLiveReplicaSets(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := v1alpha2.Backstage{
+		ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"},
+		Spec: v1alpha2.BackstageSpec{
+			Application: &v1alpha2.Application{DynamicPluginsCache: &v1alpha2.DynamicPluginsCache{}},
+		},
+	}
+	rc := BackstageReconciler{Client: NewMockClient()}
+
+	replicaSet := func(name, backstageName, key string, replicas int32) *appsv1.ReplicaSet {
+		rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1",
+			Labels: map[string]string{model.BackstageAppLabel: utils.BackstageAppLabelValue(backstageName)}}}
+		rs.Spec.Replicas = ptr.To(replicas)
+		rs.Spec.Template.Spec.Containers = []corev1.Container{{Name: "backstage-backend",
+			VolumeMounts: []corev1.VolumeMount{{Name: model.DynamicPluginsRootVolume, MountPath: "/opt/app-root/src/dynamic-plugins-root", SubPath: key}}}}
+		return rs
+	}
+	assert.NoError(t, rc.Create(ctx, replicaSet("rs-current", "bs1", "current", 2)))
+	// scaled down, but the pods are still running
+	scaledDown := replicaSet("rs-previous", "bs1", "previous", 0)
+	scaledDown.Status.Replicas = 1
+	assert.NoError(t, rc.Create(ctx, scaledDown))
+	assert.NoError(t, rc.Create(ctx, replicaSet("rs-old", "bs1", "old", 0)))
+	assert.NoError(t, rc.Create(ctx, replicaSet("rs-other", "bs2", "other", 1)))
+
+	extConf, err := rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"current", "previous"}, extConf.DynamicPluginsCacheKeys)
+
+	// not listed without the cache
+	bs.Spec.Application.DynamicPluginsCache = nil
+	extConf, err = rc.preprocessSpec(ctx, bs)
+	assert.NoError(t, err)
+	assert.Empty(t, extConf.DynamicPluginsCacheKeys)
 }
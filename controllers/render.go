@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	bsv1alpha1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha1"
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Render produces the runtime objects the Operator would create for the Backstage CR found in manifests
+// without talking to a cluster.
+// manifests is a multi-document YAML expected to contain exactly one Backstage object and, optionally,
+// ConfigMaps and Secrets referenced by it. Those are used as an in-memory source of external configuration
+// by the same preprocessing as in Reconcile.
+func Render(ctx context.Context, manifests []byte, platform model.Platform, scheme *runtime.Scheme) ([]client.Object, error) {
+
+	objects, err := decodeManifests(manifests, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// in-memory client, the objects are neither modified nor applied by the preprocessing
+	mc := fake.NewClientBuilder().WithScheme(scheme).Build()
+	var backstage *bs.Backstage
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *bs.Backstage, *bsv1alpha1.Backstage:
+			if backstage != nil {
+				return nil, fmt.Errorf("only one Backstage object expected, found %s and %s", backstage.Name, o.GetName())
+			}
+			if old, ok := o.(*bsv1alpha1.Backstage); ok {
+				backstage = &bs.Backstage{}
+				if err := old.ConvertTo(backstage); err != nil {
+					return nil, fmt.Errorf("failed to convert Backstage %s: %w", old.Name, err)
+				}
+			} else {
+				backstage = o.(*bs.Backstage)
+			}
+		case *corev1.ConfigMap, *corev1.Secret:
+			// e.g. exported from a cluster, can not be set on create
+			o.SetResourceVersion("")
+			if err := mc.Create(ctx, o); err != nil {
+				return nil, fmt.Errorf("failed to add %s %s: %w", kind(o), o.GetName(), err)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported object %s %s, only Backstage, ConfigMap and Secret are expected", kind(o), o.GetName())
+		}
+	}
+	if backstage == nil {
+		return nil, errors.New("no Backstage object found")
+	}
+
+	r := BackstageReconciler{Client: mc, Scheme: scheme, Platform: platform}
+
+	externalConfig, err := r.preprocessSpec(ctx, *backstage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preprocess backstage spec %w", err)
+	}
+
+	// owner references are not rendered, there is no live Backstage object to refer to
+	bsModel, err := model.InitObjects(ctx, *backstage, externalConfig, false, platform, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize backstage model %w", err)
+	}
+
+	result := make([]client.Object, 0, len(bsModel.RuntimeObjects))
+	for _, obj := range bsModel.RuntimeObjects {
+		gvk, err := apiutil.GVKForObject(obj.Object(), scheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GVK of %s: %w", objDispName(obj), err)
+		}
+		obj.Object().GetObjectKind().SetGroupVersionKind(gvk)
+		result = append(result, obj.Object())
+	}
+	return result, nil
+}
+
+// WriteYaml writes objects as a multi-document YAML, omitting status and other fields
+// which are populated by the cluster
+func WriteYaml(w io.Writer, objects []client.Object) error {
+	for _, obj := range objects {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s: %w", obj.GetName(), err)
+		}
+		unstructured.RemoveNestedField(u, "status")
+		unstructured.RemoveNestedField(u, "metadata", "creationTimestamp")
+
+		data, err := yaml.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeManifests decodes multi-document YAML into typed objects registered in the scheme
+func decodeManifests(manifests []byte, scheme *runtime.Scheme) ([]client.Object, error) {
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+
+	var objects []client.Object
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read YAML document: %w", err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object: %w", err)
+		}
+		cobj, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object %s", kind(obj))
+		}
+		objects = append(objects, cobj)
+	}
+	return objects, nil
+}
@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"redhat-developer/red-hat-developer-hub-operator/api/v1alpha1"
+	"redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+const renderTestManifests = `
+apiVersion: rhdh.redhat.com/v1alpha2
+kind: Backstage
+metadata:
+  name: bs1
+  namespace: ns1
+spec:
+  database:
+    enableLocalDb: false
+  application:
+    appConfig:
+      configMaps:
+        - name: my-app-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app-config
+  namespace: ns1
+data:
+  app-config.yaml: |
+    app:
+      title: Rendered
+`
+
+func renderTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha2.AddToScheme(scheme))
+	return scheme
+}
+
+func TestRender(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	objects, err := Render(context.TODO(), []byte(renderTestManifests), model.Platform{}, renderTestScheme())
+	assert.NoError(t, err)
+
+	var deployment *appsv1.Deployment
+	for _, obj := range objects {
+		assert.Equal(t, "ns1", obj.GetNamespace())
+		assert.NotEmpty(t, obj.GetObjectKind().GroupVersionKind().Kind)
+		assert.Empty(t, obj.GetOwnerReferences())
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			deployment = d
+		}
+	}
+	assert.NotNil(t, deployment)
+	assert.Equal(t, model.DeploymentName("bs1"), deployment.Name)
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "/opt/app-root/src/app-config.yaml")
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteYaml(&out, objects))
+	assert.Contains(t, out.String(), "kind: Deployment")
+	assert.NotContains(t, out.String(), "status:")
+}
+
+func TestRenderFailsOnMissingConfig(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	manifests := renderTestManifests[:bytes.Index([]byte(renderTestManifests), []byte("---"))]
+	_, err := Render(context.TODO(), []byte(manifests), model.Platform{}, renderTestScheme())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "my-app-config")
+}
+
+func TestRenderV1alpha1(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	manifests := bytes.Replace([]byte(renderTestManifests), []byte("rhdh.redhat.com/v1alpha2"), []byte("rhdh.redhat.com/v1alpha1"), 1)
+	objects, err := Render(context.TODO(), manifests, model.Platform{}, renderTestScheme())
+	assert.NoError(t, err)
+
+	found := false
+	for _, obj := range objects {
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			found = true
+			assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "/opt/app-root/src/app-config.yaml")
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRenderSameNameInOtherNamespace(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	other := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app-config
+  namespace: ns2
+data:
+  other-app-config.yaml: |
+    app:
+      title: Other
+---`
+	objects, err := Render(context.TODO(), []byte(other+renderTestManifests), model.Platform{}, renderTestScheme())
+	assert.NoError(t, err)
+
+	for _, obj := range objects {
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "/opt/app-root/src/app-config.yaml")
+			assert.NotContains(t, d.Spec.Template.Spec.Containers[0].Args, "/opt/app-root/src/other-app-config.yaml")
+		}
+	}
+}
+
+func TestRenderExportedConfig(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	// as exported from a cluster
+	manifests := bytes.Replace([]byte(renderTestManifests), []byte("  name: my-app-config\n  namespace: ns1\n"),
+		[]byte("  name: my-app-config\n  namespace: ns1\n  resourceVersion: \"42\"\n"), 1)
+	assert.Contains(t, string(manifests), "resourceVersion")
+	objects, err := Render(context.TODO(), manifests, model.Platform{}, renderTestScheme())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, objects)
+}
+
+func TestRenderLegacyStampedConfig(t *testing.T) {
+
+	utils.DefaultConfigDir = "../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+
+	// stamped by the former versions of the operator, rendered as is
+	manifests := bytes.Replace([]byte(renderTestManifests), []byte("  name: my-app-config\n  namespace: ns1\n"),
+		[]byte("  name: my-app-config\n  namespace: ns1\n  labels:\n    rhdh.redhat.com/ext-config-sync: \"true\"\n"), 1)
+	assert.Contains(t, string(manifests), model.ExtConfigSyncLabel)
+	objects, err := Render(context.TODO(), manifests, model.Platform{}, renderTestScheme())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, objects)
+}
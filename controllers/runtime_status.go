@@ -0,0 +1,530 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	openshift "github.com/openshift/api/route/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+)
+
+// the revision of the Deployment the ReplicaSet is of, set by the Deployment controller
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// container waiting reasons which mean the pod will not become ready without intervention
+var failedWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// workloadStatus is the observed state of Deployment or StatefulSet
+type workloadStatus struct {
+	status     bs.ComponentStatus
+	found      bool
+	rolledOut  bool
+	progressed bool
+}
+
+// updateRuntimeStatus reads the state of Backstage Deployment and local database StatefulSet (if enabled)
+// and their pods, and reflects it in Backstage status and Ready/Progressing/Degraded conditions.
+// Returns true if all the workloads are rolled out and available.
+func (r *BackstageReconciler) updateRuntimeStatus(ctx context.Context, backstage *bs.Backstage) (bool, error) {
+
+	bsStatus, err := r.deploymentStatus(ctx, model.DeploymentName(backstage.Name), backstage.Namespace,
+		utils.BackstageAppLabelValue(backstage.Name))
+	if err != nil {
+		return false, err
+	}
+	backstage.Status.Backstage = &bsStatus.status
+
+	statuses := []workloadStatus{bsStatus}
+	backstage.Status.LocalDb = nil
+	if backstage.Spec.IsLocalDbEnabled() {
+		dbStatus, err := r.statefulSetStatus(ctx, model.DbStatefulSetName(backstage.Name), backstage.Namespace,
+			utils.BackstageDbAppLabelValue(backstage.Name))
+		if err != nil {
+			return false, err
+		}
+		backstage.Status.LocalDb = &dbStatus.status
+		statuses = append(statuses, dbStatus)
+	}
+
+	ready := true
+	stalled := false
+	var failures []string
+	var failedReason string
+	for _, s := range statuses {
+		if !s.found || !s.rolledOut {
+			ready = false
+		}
+		if !s.found {
+			stalled = true
+			failures = append(failures, fmt.Sprintf("%s not found", s.status.Name))
+			failedReason = string(bs.BackstageConditionReasonWorkloadNotFound)
+		} else if s.status.Reason != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", s.status.Name, s.status.Message))
+			if failedReason == "" {
+				failedReason = s.status.Reason
+			}
+		} else if !s.progressed {
+			stalled = true
+		}
+	}
+
+	if ready {
+		setStatusCondition(backstage, bs.BackstageConditionTypeReady, metav1.ConditionTrue, bs.BackstageConditionReasonRolloutComplete, "")
+		setStatusCondition(backstage, bs.BackstageConditionTypeProgressing, metav1.ConditionFalse, bs.BackstageConditionReasonRolloutComplete, "")
+	} else {
+		setStatusCondition(backstage, bs.BackstageConditionTypeReady, metav1.ConditionFalse, bs.BackstageConditionReasonRolloutInProgress,
+			"Backstage runtime is not available yet")
+		progressing := metav1.ConditionTrue
+		if stalled || len(failures) > 0 {
+			progressing = metav1.ConditionFalse
+		}
+		setStatusCondition(backstage, bs.BackstageConditionTypeProgressing, progressing, bs.BackstageConditionReasonRolloutInProgress, "")
+	}
+
+	if len(failures) > 0 {
+		setStatusCondition(backstage, bs.BackstageConditionTypeDegraded, metav1.ConditionTrue, bs.BackstageConditionReason(failedReason),
+			strings.Join(failures, "; "))
+	} else {
+		setStatusCondition(backstage, bs.BackstageConditionTypeDegraded, metav1.ConditionFalse, bs.BackstageConditionReasonAsExpected, "")
+	}
+
+	if err := r.updatePluginsStatus(ctx, backstage); err != nil {
+		return false, err
+	}
+
+	return ready, nil
+}
+
+// updatePluginsStatus reflects the state of the install-dynamic-plugins init container of the Backstage pods
+// in the PluginsInstalled condition, removed if the pods have no such init container
+func (r *BackstageReconciler) updatePluginsStatus(ctx context.Context, backstage *bs.Backstage) error {
+	pods := &corev1.PodList{}
+	if err := r.apiReader().List(ctx, pods, client.InNamespace(backstage.Namespace),
+		client.MatchingLabels{model.BackstageAppLabel: utils.BackstageAppLabelValue(backstage.Name)}); err != nil {
+		return fmt.Errorf("failed to list pods of %s: %w", backstage.Name, err)
+	}
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := r.apiReader().List(ctx, replicaSets, client.InNamespace(backstage.Namespace),
+		client.MatchingLabels{model.BackstageAppLabel: utils.BackstageAppLabelValue(backstage.Name)}); err != nil {
+		return fmt.Errorf("failed to list ReplicaSets of %s: %w", backstage.Name, err)
+	}
+	status, reason, msg := pluginsInstallStatus(currentPods(pods.Items, replicaSets.Items))
+	if status == "" {
+		meta.RemoveStatusCondition(&backstage.Status.Conditions, string(bs.BackstageConditionTypePluginsInstalled))
+		return nil
+	}
+	setStatusCondition(backstage, bs.BackstageConditionTypePluginsInstalled, status, reason, msg)
+	return nil
+}
+
+// currentPods returns the pods of the current Pod template, i.e. of the newest ReplicaSet (all if none found),
+// not terminating, so the pods of the previous rollout do not affect the status
+func currentPods(pods []corev1.Pod, replicaSets []appsv1.ReplicaSet) []corev1.Pod {
+	hash := ""
+	newest := int64(-1)
+	for _, rs := range replicaSets {
+		revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+		if err != nil || revision <= newest {
+			continue
+		}
+		newest = revision
+		hash = rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	}
+	var current []corev1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if hash != "" && pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] != hash {
+			continue
+		}
+		current = append(current, pod)
+	}
+	return current
+}
+
+// the line logged by install-dynamic-plugins before installing the package
+var installingPluginRegexp = regexp.MustCompile(`=+ Installing dynamic plugin (\S+)`)
+
+// pluginsInstallStatus returns the PluginsInstalled condition status, reason and message from the state of
+// the install-dynamic-plugins init container of the pods: failed if it failed in any pod,
+// installed if it completed in any pod, in progress otherwise. Empty status if there is no such init container.
+func pluginsInstallStatus(pods []corev1.Pod) (metav1.ConditionStatus, bs.BackstageConditionReason, string) {
+	var status metav1.ConditionStatus
+	for _, pod := range pods {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			if cs.Name != model.DynamicPluginsInitContainerName {
+				continue
+			}
+			terminated := cs.State.Terminated
+			if terminated == nil && cs.State.Waiting != nil {
+				// restarting after failure
+				terminated = cs.LastTerminationState.Terminated
+			}
+			switch {
+			case terminated != nil && terminated.ExitCode != 0:
+				return metav1.ConditionFalse, bs.BackstageConditionReasonPluginInstallFailed,
+					fmt.Sprintf("pod %s: %s", pod.Name, installFailure(terminated))
+			case terminated != nil:
+				status = metav1.ConditionTrue
+			case status == "":
+				status = metav1.ConditionUnknown
+			}
+		}
+	}
+	switch status {
+	case metav1.ConditionTrue:
+		return status, bs.BackstageConditionReasonPluginsInstalled, ""
+	case metav1.ConditionUnknown:
+		return status, bs.BackstageConditionReasonPluginsInstalling, "dynamic plugins are being installed"
+	}
+	return "", "", ""
+}
+
+// installFailure describes the failure of the install-dynamic-plugins init container
+// with the package being installed and the last line of the termination message (the error, as a rule)
+func installFailure(terminated *corev1.ContainerStateTerminated) string {
+	lines := strings.Split(strings.TrimSpace(terminated.Message), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if matches := installingPluginRegexp.FindAllStringSubmatch(terminated.Message, -1); len(matches) > 0 {
+		pkg := matches[len(matches)-1][1]
+		return fmt.Sprintf("failed to install plugin %s: %s", pkg, last)
+	}
+	if last == "" {
+		last = terminated.Reason
+	}
+	return fmt.Sprintf("failed to install plugins, exit code %d: %s", terminated.ExitCode, last)
+}
+
+func (r *BackstageReconciler) deploymentStatus(ctx context.Context, name, ns, appLabel string) (workloadStatus, error) {
+	ws := workloadStatus{status: bs.ComponentStatus{Name: name}}
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return ws, nil
+		}
+		return ws, fmt.Errorf("failed to get deployment %s: %w", name, err)
+	}
+	ws.found = true
+	ws.status.Replicas = desiredReplicas(deployment.Spec.Replicas)
+	ws.status.AvailableReplicas = deployment.Status.AvailableReplicas
+	ws.rolledOut, ws.progressed = deploymentRolledOut(deployment)
+
+	if err := r.podsFailure(ctx, &ws.status, ns, appLabel); err != nil {
+		return ws, err
+	}
+	return ws, nil
+}
+
+func (r *BackstageReconciler) statefulSetStatus(ctx context.Context, name, ns, appLabel string) (workloadStatus, error) {
+	ws := workloadStatus{status: bs.ComponentStatus{Name: name}}
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, statefulSet); err != nil {
+		if errors.IsNotFound(err) {
+			return ws, nil
+		}
+		return ws, fmt.Errorf("failed to get statefulset %s: %w", name, err)
+	}
+	ws.found = true
+	ws.status.Replicas = desiredReplicas(statefulSet.Spec.Replicas)
+	ws.status.AvailableReplicas = statefulSet.Status.AvailableReplicas
+	ws.rolledOut = statefulSetRolledOut(statefulSet)
+	ws.progressed = true
+
+	if err := r.podsFailure(ctx, &ws.status, ns, appLabel); err != nil {
+		return ws, err
+	}
+	return ws, nil
+}
+
+// resolveURL returns the URL Backstage is exposed at, or empty string if not exposed (yet).
+// It is taken from the first of the following admitting the host:
+// OpenShift Route (on OpenShift), Gateway API HTTPRoute (if Gateway API available) and Ingress (on non-OpenShift).
+func (r *BackstageReconciler) resolveURL(ctx context.Context, backstage *bs.Backstage) (string, error) {
+	if r.Platform.IsOpenShift && backstage.Spec.IsRouteEnabled() {
+		route := &openshift.Route{}
+		if found, err := r.getIfExists(ctx, route, model.RouteName(backstage.Name), backstage.Namespace); err != nil {
+			return "", err
+		} else if url := routeURL(route); found && url != "" {
+			return url, nil
+		}
+	}
+
+	if r.Platform.HasGatewayAPI && backstage.Spec.IsHTTPRouteEnabled() {
+		httpRoute := &unstructured.Unstructured{}
+		httpRoute.SetGroupVersionKind(model.HTTPRouteGVK)
+		if found, err := r.getIfExists(ctx, httpRoute, model.HTTPRouteName(backstage.Name), backstage.Namespace); err != nil {
+			return "", err
+		} else if found {
+			gateway := &unstructured.Unstructured{}
+			gateway.SetGroupVersionKind(gatewayGVK)
+			name, ns, _ := httpRouteParent(httpRoute)
+			if _, err := r.getIfExists(ctx, gateway, name, ns); err != nil {
+				return "", err
+			}
+			if url := httpRouteURL(httpRoute, gateway); url != "" {
+				return url, nil
+			}
+		}
+	}
+
+	if !r.Platform.IsOpenShift && backstage.Spec.IsIngressEnabled() {
+		ingress := &networkingv1.Ingress{}
+		if found, err := r.getIfExists(ctx, ingress, model.IngressName(backstage.Name), backstage.Namespace); err != nil {
+			return "", err
+		} else if found {
+			return ingressURL(ingress), nil
+		}
+	}
+
+	return "", nil
+}
+
+// getIfExists reads the object by name and namespace and returns false if not found
+func (r *BackstageReconciler) getIfExists(ctx context.Context, obj client.Object, name, ns string) (bool, error) {
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, name, err)
+	}
+	return true, nil
+}
+
+// routeURL returns the URL of the first host admitted by the router, or empty string if none
+func routeURL(route *openshift.Route) string {
+	scheme := "http"
+	if route.Spec.TLS != nil {
+		scheme = "https"
+	}
+	for _, ingress := range route.Status.Ingress {
+		if ingress.Host == "" {
+			continue
+		}
+		for _, c := range ingress.Conditions {
+			if c.Type == openshift.RouteAdmitted && c.Status == corev1.ConditionTrue {
+				return fmt.Sprintf("%s://%s", scheme, ingress.Host)
+			}
+		}
+	}
+	return ""
+}
+
+// ingressURL returns the URL of the Ingress host, or of its load balancer if no host specified,
+// or empty string if the load balancer is not provisioned yet
+func ingressURL(ingress *networkingv1.Ingress) string {
+	if len(ingress.Status.LoadBalancer.Ingress) == 0 {
+		return ""
+	}
+	host := ""
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			host = rule.Host
+			break
+		}
+	}
+	if host == "" {
+		lb := ingress.Status.LoadBalancer.Ingress[0]
+		host = lb.Hostname
+		if host == "" {
+			host = lb.IP
+		}
+	}
+	if host == "" {
+		return ""
+	}
+	scheme := "http"
+	for _, tls := range ingress.Spec.TLS {
+		if len(tls.Hosts) == 0 || slices.Contains(tls.Hosts, host) {
+			scheme = "https"
+		}
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+var gatewayGVK = schema.GroupVersionKind{Group: model.HTTPRouteGVK.Group, Version: model.HTTPRouteGVK.Version, Kind: "Gateway"}
+
+// httpRouteParent returns the name, namespace and section name of the (first) Gateway the HTTPRoute is attached to
+func httpRouteParent(httpRoute *unstructured.Unstructured) (string, string, string) {
+	parentRefs, _, _ := unstructured.NestedSlice(httpRoute.Object, "spec", "parentRefs")
+	if len(parentRefs) == 0 {
+		return "", "", ""
+	}
+	parentRef, _ := parentRefs[0].(map[string]interface{})
+	name, _, _ := unstructured.NestedString(parentRef, "name")
+	ns, _, _ := unstructured.NestedString(parentRef, "namespace")
+	if ns == "" {
+		ns = httpRoute.GetNamespace()
+	}
+	section, _, _ := unstructured.NestedString(parentRef, "sectionName")
+	return name, ns, section
+}
+
+// httpRouteURL returns the URL of the HTTPRoute accepted by its parent Gateway, or empty string if not accepted (yet).
+// The host is taken from the HTTPRoute hostnames, or the Gateway listener hostname, or the Gateway address.
+// The scheme is https if the Gateway listener protocol is HTTPS.
+func httpRouteURL(httpRoute *unstructured.Unstructured, gateway *unstructured.Unstructured) string {
+	accepted := false
+	parents, _, _ := unstructured.NestedSlice(httpRoute.Object, "status", "parents")
+	for _, p := range parents {
+		parent, _ := p.(map[string]interface{})
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			if cond["type"] == "Accepted" && cond["status"] == string(metav1.ConditionTrue) {
+				accepted = true
+			}
+		}
+	}
+	if !accepted {
+		return ""
+	}
+
+	_, _, section := httpRouteParent(httpRoute)
+	var listener map[string]interface{}
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, l := range listeners {
+		lst, _ := l.(map[string]interface{})
+		if section == "" || lst["name"] == section {
+			listener = lst
+			break
+		}
+	}
+
+	host := ""
+	if hostnames, _, _ := unstructured.NestedStringSlice(httpRoute.Object, "spec", "hostnames"); len(hostnames) > 0 {
+		host = hostnames[0]
+	}
+	if h, _, _ := unstructured.NestedString(listener, "hostname"); host == "" && !strings.HasPrefix(h, "*") {
+		host = h
+	}
+	if host == "" {
+		addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+		if len(addresses) > 0 {
+			address, _ := addresses[0].(map[string]interface{})
+			host, _, _ = unstructured.NestedString(address, "value")
+		}
+	}
+	if host == "" || strings.HasPrefix(host, "*") {
+		return ""
+	}
+
+	scheme := "http"
+	if protocol, _, _ := unstructured.NestedString(listener, "protocol"); protocol == "HTTPS" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+// podsFailure sets the reason and message of the first failing pod found by app label, if any
+func (r *BackstageReconciler) podsFailure(ctx context.Context, status *bs.ComponentStatus, ns, appLabel string) error {
+	pods := &corev1.PodList{}
+	if err := r.apiReader().List(ctx, pods, client.InNamespace(ns), client.MatchingLabels{model.BackstageAppLabel: appLabel}); err != nil {
+		return fmt.Errorf("failed to list pods of %s: %w", status.Name, err)
+	}
+	for _, pod := range pods.Items {
+		if reason, msg := podFailure(&pod); reason != "" {
+			status.Reason = reason
+			status.Message = fmt.Sprintf("pod %s: %s", pod.Name, msg)
+			return nil
+		}
+	}
+	return nil
+}
+
+// podFailure returns the reason and message if the pod is failing, or empty strings otherwise.
+// Init containers are checked first, as main containers do not start until they succeed.
+func podFailure(pod *corev1.Pod) (string, string) {
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			return string(bs.BackstageConditionReasonInitContainerFailed),
+				fmt.Sprintf("init container %s terminated with exit code %d: %s %s", cs.Name, t.ExitCode, t.Reason, t.Message)
+		}
+		if w := cs.State.Waiting; w != nil && failedWaitingReasons[w.Reason] {
+			if w.Reason == "CrashLoopBackOff" {
+				return string(bs.BackstageConditionReasonInitContainerFailed),
+					fmt.Sprintf("init container %s: %s %s", cs.Name, w.Reason, w.Message)
+			}
+			return w.Reason, fmt.Sprintf("init container %s: %s", cs.Name, w.Message)
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if w := cs.State.Waiting; w != nil && failedWaitingReasons[w.Reason] {
+			return w.Reason, fmt.Sprintf("container %s: %s", cs.Name, w.Message)
+		}
+	}
+	return "", ""
+}
+
+// deploymentRolledOut returns whether the latest Deployment spec is rolled out and all the replicas are available,
+// and whether the rollout is (still) making progress
+func deploymentRolledOut(deployment *appsv1.Deployment) (bool, bool) {
+	progressed := true
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			progressed = false
+		}
+		if c.Type == appsv1.DeploymentReplicaFailure && c.Status == corev1.ConditionTrue {
+			progressed = false
+		}
+	}
+	desired := desiredReplicas(deployment.Spec.Replicas)
+	st := deployment.Status
+	rolledOut := st.ObservedGeneration >= deployment.Generation &&
+		st.UpdatedReplicas == desired && st.Replicas == desired && st.AvailableReplicas == desired
+	return rolledOut, progressed
+}
+
+// statefulSetRolledOut returns whether the latest StatefulSet spec is rolled out and all the replicas are available
+func statefulSetRolledOut(statefulSet *appsv1.StatefulSet) bool {
+	desired := desiredReplicas(statefulSet.Spec.Replicas)
+	st := statefulSet.Status
+	return st.ObservedGeneration >= statefulSet.Generation &&
+		st.UpdatedReplicas == desired && st.AvailableReplicas == desired &&
+		(st.UpdateRevision == "" || st.CurrentRevision == st.UpdateRevision)
+}
+
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,252 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	openshift "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodFailure(t *testing.T) {
+
+	pod := &corev1.Pod{}
+	reason, _ := podFailure(pod)
+	assert.Empty(t, reason)
+
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "backstage-backend",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}}
+	reason, _ = podFailure(pod)
+	assert.Empty(t, reason)
+
+	pod.Status.ContainerStatuses[0].State.Waiting.Reason = "ImagePullBackOff"
+	reason, msg := podFailure(pod)
+	assert.Equal(t, "ImagePullBackOff", reason)
+	assert.Contains(t, msg, "backstage-backend")
+
+	// init container failure takes precedence
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "install-dynamic-plugins",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+	}}
+	reason, msg = podFailure(pod)
+	assert.Equal(t, string(bs.BackstageConditionReasonInitContainerFailed), reason)
+	assert.Contains(t, msg, "install-dynamic-plugins")
+
+	pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	reason, _ = podFailure(pod)
+	assert.Equal(t, string(bs.BackstageConditionReasonInitContainerFailed), reason)
+
+	pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+	reason, _ = podFailure(pod)
+	assert.Equal(t, "ImagePullBackOff", reason)
+}
+
+func TestPluginsInstallStatus(t *testing.T) {
+
+	// no install-dynamic-plugins init container
+	status, _, _ := pluginsInstallStatus([]corev1.Pod{{}})
+	assert.Empty(t, status)
+
+	pod := corev1.Pod{}
+	pod.Name = "backstage-1"
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "install-dynamic-plugins",
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+	status, reason, _ := pluginsInstallStatus([]corev1.Pod{pod})
+	assert.Equal(t, metav1.ConditionUnknown, status)
+	assert.Equal(t, bs.BackstageConditionReasonPluginsInstalling, reason)
+
+	pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+	status, reason, _ = pluginsInstallStatus([]corev1.Pod{pod})
+	assert.Equal(t, metav1.ConditionTrue, status)
+	assert.Equal(t, bs.BackstageConditionReasonPluginsInstalled, reason)
+
+	// failed and restarting, the failing package taken from the log tail
+	pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	pod.Status.InitContainerStatuses[0].LastTerminationState = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+		ExitCode: 1,
+		Reason:   "Error",
+		Message: "\n======= Installing dynamic plugin ./dynamic-plugins/dist/plugin-a\n\t==> Successfully installed" +
+			"\n======= Installing dynamic plugin @org/plugin-b@1.0.0\n" +
+			"InstallException: Integrity check failed for package @org/plugin-b@1.0.0\n",
+	}}
+	status, reason, msg := pluginsInstallStatus([]corev1.Pod{pod})
+	assert.Equal(t, metav1.ConditionFalse, status)
+	assert.Equal(t, bs.BackstageConditionReasonPluginInstallFailed, reason)
+	assert.Equal(t, "pod backstage-1: failed to install plugin @org/plugin-b@1.0.0: "+
+		"InstallException: Integrity check failed for package @org/plugin-b@1.0.0", msg)
+
+	// no log
+	pod.Status.InitContainerStatuses[0].LastTerminationState.Terminated.Message = ""
+	_, _, msg = pluginsInstallStatus([]corev1.Pod{pod})
+	assert.Equal(t, "pod backstage-1: failed to install plugins, exit code 1: Error", msg)
+}
+
+func TestCurrentPods(t *testing.T) {
+
+	rs := func(revision, hash string) appsv1.ReplicaSet {
+		rs := appsv1.ReplicaSet{}
+		rs.Annotations = map[string]string{deploymentRevisionAnnotation: revision}
+		rs.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: hash}
+		return rs
+	}
+	pod := func(name, hash string) corev1.Pod {
+		pod := corev1.Pod{}
+		pod.Name = name
+		pod.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: hash}
+		return pod
+	}
+	terminating := pod("new-terminating", "h2")
+	terminating.DeletionTimestamp = &metav1.Time{}
+	pods := []corev1.Pod{pod("old", "h1"), pod("new", "h2"), terminating}
+
+	names := func(pods []corev1.Pod) []string {
+		var names []string
+		for _, p := range pods {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	// the pods of the previous rollout are ignored
+	assert.Equal(t, []string{"new"}, names(currentPods(pods, []appsv1.ReplicaSet{rs("10", "h2"), rs("9", "h1")})))
+	// no ReplicaSet found
+	assert.Equal(t, []string{"old", "new"}, names(currentPods(pods, nil)))
+}
+
+func TestDeploymentRolledOut(t *testing.T) {
+
+	deployment := &appsv1.Deployment{}
+	deployment.Generation = 2
+	deployment.Spec.Replicas = ptr.To(int32(2))
+	deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+
+	rolledOut, progressed := deploymentRolledOut(deployment)
+	assert.False(t, rolledOut)
+	assert.True(t, progressed)
+
+	deployment.Status.ObservedGeneration = 2
+	deployment.Status.AvailableReplicas = 1
+	rolledOut, _ = deploymentRolledOut(deployment)
+	assert.False(t, rolledOut)
+
+	deployment.Status.AvailableReplicas = 2
+	rolledOut, _ = deploymentRolledOut(deployment)
+	assert.True(t, rolledOut)
+
+	deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+	}}
+	_, progressed = deploymentRolledOut(deployment)
+	assert.False(t, progressed)
+}
+
+func TestStatefulSetRolledOut(t *testing.T) {
+
+	statefulSet := &appsv1.StatefulSet{}
+	statefulSet.Status = appsv1.StatefulSetStatus{UpdatedReplicas: 1, AvailableReplicas: 1, CurrentRevision: "r1", UpdateRevision: "r2"}
+	assert.False(t, statefulSetRolledOut(statefulSet))
+
+	statefulSet.Status.CurrentRevision = "r2"
+	assert.True(t, statefulSetRolledOut(statefulSet))
+}
+
+func TestRouteURL(t *testing.T) {
+
+	route := &openshift.Route{}
+	assert.Empty(t, routeURL(route))
+
+	route.Status.Ingress = []openshift.RouteIngress{{
+		Host:       "backstage.apps.example.com",
+		Conditions: []openshift.RouteIngressCondition{{Type: openshift.RouteAdmitted, Status: corev1.ConditionFalse}},
+	}}
+	assert.Empty(t, routeURL(route))
+
+	route.Status.Ingress[0].Conditions[0].Status = corev1.ConditionTrue
+	assert.Equal(t, "http://backstage.apps.example.com", routeURL(route))
+
+	route.Spec.TLS = &openshift.TLSConfig{Termination: openshift.TLSTerminationEdge}
+	assert.Equal(t, "https://backstage.apps.example.com", routeURL(route))
+}
+
+func TestIngressURL(t *testing.T) {
+
+	ingress := &networkingv1.Ingress{}
+	ingress.Spec.Rules = []networkingv1.IngressRule{{Host: "backstage.example.com"}}
+	assert.Empty(t, ingressURL(ingress))
+
+	ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}}
+	assert.Equal(t, "http://backstage.example.com", ingressURL(ingress))
+
+	ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{"backstage.example.com"}, SecretName: "tls"}}
+	assert.Equal(t, "https://backstage.example.com", ingressURL(ingress))
+
+	ingress.Spec.Rules = []networkingv1.IngressRule{{}}
+	ingress.Spec.TLS = nil
+	assert.Equal(t, "http://10.0.0.1", ingressURL(ingress))
+}
+
+func TestHTTPRouteURL(t *testing.T) {
+
+	httpRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "backstage-bs1", "namespace": "ns1"},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": "gw", "sectionName": "https"}},
+		},
+	}}
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "protocol": "HTTP", "hostname": "*.example.com"},
+				map[string]interface{}{"name": "https", "protocol": "HTTPS", "hostname": "*.example.com"},
+			},
+		},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"type": "IPAddress", "value": "10.0.0.1"}},
+		},
+	}}
+
+	name, ns, section := httpRouteParent(httpRoute)
+	assert.Equal(t, "gw", name)
+	assert.Equal(t, "ns1", ns)
+	assert.Equal(t, "https", section)
+
+	// not accepted
+	assert.Empty(t, httpRouteURL(httpRoute, gateway))
+
+	httpRoute.Object["status"] = map[string]interface{}{
+		"parents": []interface{}{map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+		}},
+	}
+	// wildcard listener hostname -> gateway address
+	assert.Equal(t, "https://10.0.0.1", httpRouteURL(httpRoute, gateway))
+
+	httpRoute.Object["spec"].(map[string]interface{})["hostnames"] = []interface{}{"backstage.example.com"}
+	assert.Equal(t, "https://backstage.example.com", httpRouteURL(httpRoute, gateway))
+}
@@ -17,27 +17,23 @@ package controller
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
-
-	"k8s.io/client-go/util/retry"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"k8s.io/utils/ptr"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	bs "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 
 	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
-const AutoSyncEnvVar = "EXT_CONF_SYNC_backstage"
-
 // Add additional details to the Backstage Spec helping in making Backstage RuntimeObjects Model
 // Validates Backstage Spec and fails fast if something not correct
 func (r *BackstageReconciler) preprocessSpec(ctx context.Context, backstage bs.Backstage) (model.ExternalConfig, error) {
@@ -52,7 +48,7 @@ func (r *BackstageReconciler) preprocessSpec(ctx context.Context, backstage bs.B
 	if bsSpec.RawRuntimeConfig != nil {
 		if bsSpec.RawRuntimeConfig.BackstageConfigName != "" {
 			cm := &corev1.ConfigMap{}
-			if err := r.addExtConfig(&result, ctx, cm, backstage.Name, bsSpec.RawRuntimeConfig.BackstageConfigName, ns); err != nil {
+			if err := r.addExtConfig(&result, ctx, cm, bsSpec.RawRuntimeConfig.BackstageConfigName, "", true, ns); err != nil {
 				return result, err
 			}
 			for key, value := range cm.Data {
@@ -61,7 +57,7 @@ func (r *BackstageReconciler) preprocessSpec(ctx context.Context, backstage bs.B
 		}
 		if bsSpec.RawRuntimeConfig.LocalDbConfigName != "" {
 			cm := &corev1.ConfigMap{}
-			if err := r.addExtConfig(&result, ctx, cm, backstage.Name, bsSpec.RawRuntimeConfig.LocalDbConfigName, ns); err != nil {
+			if err := r.addExtConfig(&result, ctx, cm, bsSpec.RawRuntimeConfig.LocalDbConfigName, "", true, ns); err != nil {
 				return result, err
 			}
 			for key, value := range cm.Data {
@@ -76,116 +72,238 @@ func (r *BackstageReconciler) preprocessSpec(ctx context.Context, backstage bs.B
 
 	// Process AppConfigs
 	if bsSpec.Application.AppConfig != nil {
+		// hot reloaded app-configs are not hashed, so their changes do not restart the Pods
+		hashed := !bsSpec.Application.AppConfig.HotReload
 		for _, ac := range bsSpec.Application.AppConfig.ConfigMaps {
-			cm := &corev1.ConfigMap{}
-			if err := r.addExtConfig(&result, ctx, cm, backstage.Name, ac.Name, ns); err != nil {
+			cm, err := r.addExtConfigRef(&result, ctx, &corev1.ConfigMap{}, backstage, ac, hashed)
+			if err != nil {
 				return result, err
 			}
-			result.AppConfigs[ac.Name] = *cm
+			result.AppConfigs[model.ExternalConfigKey(ac)] = *cm.(*corev1.ConfigMap)
+		}
+	}
+
+	// Process inline AppConfig, hashed as the ConfigMaps, so its changes restart the Pods (unless hot reloaded)
+	inline, err := model.NewInlineAppConfigMap(backstage)
+	if err != nil {
+		return result, err
+	}
+	if inline != nil && !bsSpec.Application.AppConfig.HotReload {
+		if err := result.AddToSyncedConfig(inline, ""); err != nil {
+			return result, fmt.Errorf("failed to add to synced %s: %s", inline.Name, err)
 		}
 	}
 
 	// Process ConfigMapFiles
 	if bsSpec.Application.ExtraFiles != nil && bsSpec.Application.ExtraFiles.ConfigMaps != nil {
 		for _, ef := range bsSpec.Application.ExtraFiles.ConfigMaps {
-			cm := &corev1.ConfigMap{}
-			if err := r.addExtConfig(&result, ctx, cm, backstage.Name, ef.Name, ns); err != nil {
+			cm, err := r.addExtConfigRef(&result, ctx, &corev1.ConfigMap{}, backstage, ef, true)
+			if err != nil {
 				return result, err
 			}
-			result.ExtraFileConfigMaps[cm.Name] = *cm
+			result.ExtraFileConfigMaps[model.ExternalConfigKey(ef)] = *cm.(*corev1.ConfigMap)
 		}
 	}
 
 	// Process SecretFiles
 	if bsSpec.Application.ExtraFiles != nil && bsSpec.Application.ExtraFiles.Secrets != nil {
 		for _, ef := range bsSpec.Application.ExtraFiles.Secrets {
-			secret := &corev1.Secret{}
-			if err := r.addExtConfig(&result, ctx, secret, backstage.Name, ef.Name, ns); err != nil {
+			secret, err := r.addExtConfigRef(&result, ctx, &corev1.Secret{}, backstage, ef, true)
+			if err != nil {
 				return result, err
 			}
-			result.ExtraFileSecrets[secret.Name] = *secret
+			result.ExtraFileSecrets[model.ExternalConfigKey(ef)] = *secret.(*corev1.Secret)
 		}
 	}
 
 	// Process ConfigMapEnvs
 	if bsSpec.Application.ExtraEnvs != nil && bsSpec.Application.ExtraEnvs.ConfigMaps != nil {
 		for _, ee := range bsSpec.Application.ExtraEnvs.ConfigMaps {
-			cm := &corev1.ConfigMap{}
-			if err := r.addExtConfig(&result, ctx, cm, backstage.Name, ee.Name, ns); err != nil {
+			cm, err := r.addExtConfigRef(&result, ctx, &corev1.ConfigMap{}, backstage, ee, true)
+			if err != nil {
 				return result, err
 			}
-			result.ExtraEnvConfigMaps[cm.Name] = *cm
+			result.ExtraEnvConfigMaps[model.ExternalConfigKey(ee)] = *cm.(*corev1.ConfigMap)
 		}
 	}
 
 	// Process SecretEnvs
 	if bsSpec.Application.ExtraEnvs != nil && bsSpec.Application.ExtraEnvs.Secrets != nil {
 		for _, ee := range bsSpec.Application.ExtraEnvs.Secrets {
-			secret := &corev1.Secret{}
-			if err := r.addExtConfig(&result, ctx, secret, backstage.Name, ee.Name, ns); err != nil {
+			secret, err := r.addExtConfigRef(&result, ctx, &corev1.Secret{}, backstage, ee, true)
+			if err != nil {
 				return result, err
 			}
-			result.ExtraEnvSecrets[secret.Name] = *secret
+			result.ExtraEnvSecrets[model.ExternalConfigKey(ee)] = *secret.(*corev1.Secret)
 		}
 	}
 
 	// Process DynamicPlugins
-	if bsSpec.Application.DynamicPluginsConfigMapName != "" {
+	if name := bsSpec.Application.DynamicPluginsConfigMapName; name != "" {
 		cm := &corev1.ConfigMap{}
-		if err := r.addExtConfig(&result, ctx, cm, backstage.Name, bsSpec.Application.DynamicPluginsConfigMapName, ns); err != nil {
+		hashed := backstage.IsRestartOnChange("ConfigMap", ns, name, restartOnConfigChangeDefault())
+		if err := r.addExtConfig(&result, ctx, cm, name, "", hashed, ns); err != nil {
 			return result, err
 		}
 		result.DynamicPlugins = *cm
 	}
 
-	return result, nil
-}
-
-func (r *BackstageReconciler) addExtConfig(config *model.ExternalConfig, ctx context.Context, obj client.Object, backstageName, objectName, ns string) error {
-
-	lg := log.FromContext(ctx)
-
-	// use RetryOnConflict to avoid possible Conflict error which may be caused mostly by other call of this function
-	// https://pkg.go.dev/k8s.io/client-go/util/retry#RetryOnConflict.
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-
-		if err := r.Get(ctx, types.NamespacedName{Name: objectName, Namespace: ns}, obj); err != nil {
-			if _, ok := obj.(*corev1.Secret); ok && errors.IsForbidden(err) {
-				return fmt.Errorf("warning: Secrets GET is forbidden, updating Secrets may not cause Pod recreating")
+	// Process DynamicPluginsRegistry
+	if registry := bsSpec.Application.DynamicPluginsRegistry; registry != nil {
+		if registry.Npmrc != nil {
+			secret, err := r.addExtConfigRef(&result, ctx, &corev1.Secret{}, backstage, *registry.Npmrc, true)
+			if err != nil {
+				return result, err
+			}
+			result.DynamicPluginsNpmrc = *secret.(*corev1.Secret)
+		}
+		if registry.CABundle != nil {
+			cm, err := r.addExtConfigRef(&result, ctx, &corev1.ConfigMap{}, backstage, *registry.CABundle, true)
+			if err != nil {
+				return result, err
 			}
-			return fmt.Errorf("failed to get external config from %s: %s", objectName, err)
+			result.DynamicPluginsCABundle = *cm.(*corev1.ConfigMap)
 		}
+		if registry.OCIAuth != nil {
+			secret, err := r.addExtConfigRef(&result, ctx, &corev1.Secret{}, backstage, *registry.OCIAuth, true)
+			if err != nil {
+				return result, err
+			}
+			result.DynamicPluginsOCIAuth = *secret.(*corev1.Secret)
+		}
+	}
 
-		if err := config.AddToSyncedConfig(obj); err != nil {
-			return fmt.Errorf("failed to add to synced %s: %s", obj.GetName(), err)
+	// Process inline DynamicPlugins, hashed as the ConfigMap, so its changes restart the Pods reinstalling the plugins
+	inlinePlugins, err := model.InlineDynamicPlugins(bsSpec)
+	if err != nil {
+		return result, err
+	}
+	if inlinePlugins != "" {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: model.DynamicPluginsDefaultName(backstage.Name), Namespace: ns},
+			Data:       map[string]string{model.DynamicPluginsFile: inlinePlugins},
+		}
+		if err := result.AddToSyncedConfig(cm, ""); err != nil {
+			return result, fmt.Errorf("failed to add to synced %s: %s", cm.Name, err)
 		}
+	}
+
+	// Process DynamicPluginsCache, the entries used by the live ReplicaSets must not be removed from the cache
+	if bsSpec.Application.DynamicPluginsCache != nil {
+		if result.DynamicPluginsCacheKeys, err = r.dynamicPluginsCacheKeys(ctx, backstage); err != nil {
+			return result, err
+		}
+	}
 
-		if obj.GetLabels() == nil {
-			obj.SetLabels(map[string]string{})
+	return result, nil
+}
+
+// dynamicPluginsCacheKeys returns the dynamic plugins cache entries used by the ReplicaSets of the Backstage Deployment
+// which have (or are about to have) pods
+func (r *BackstageReconciler) dynamicPluginsCacheKeys(ctx context.Context, backstage bs.Backstage) ([]string, error) {
+	rsList := appsv1.ReplicaSetList{}
+	if err := r.apiReader().List(ctx, &rsList, client.InNamespace(backstage.Namespace),
+		client.MatchingLabels{model.BackstageAppLabel: utils.BackstageAppLabelValue(backstage.Name)}); err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets of %s: %w", backstage.Name, err)
+	}
+	var keys []string
+	for _, rs := range rsList.Items {
+		if ptr.Deref(rs.Spec.Replicas, 0) == 0 && rs.Status.Replicas == 0 {
+			continue
 		}
-		if obj.GetAnnotations() == nil {
-			obj.SetAnnotations(map[string]string{})
+		if key := model.DynamicPluginsCacheKey(rs.Spec.Template.Spec); key != "" {
+			keys = append(keys, key)
 		}
+	}
+	return keys, nil
+}
 
-		autoSync := true
-		autoSyncStr, ok := os.LookupEnv(AutoSyncEnvVar)
-		if ok {
-			autoSync, _ = strconv.ParseBool(autoSyncStr)
+// addExtConfigRef reads the external config object referred by ref (see addExtConfig).
+// If the object is of another namespace, the reference has to be allowed by a BackstageReferenceGrant, and the mirror
+// of the object (see model.Mirror) is returned and added to the config to be applied into the Backstage namespace
+func (r *BackstageReconciler) addExtConfigRef(config *model.ExternalConfig, ctx context.Context, obj client.Object, backstage bs.Backstage, ref bs.ObjectKeyRef, hashed bool) (client.Object, error) {
+
+	ns := backstage.RefNamespace(ref)
+	if ns != backstage.Namespace {
+		if err := r.checkReferenceGrant(ctx, backstage.Namespace, obj, ns, ref.Name); err != nil {
+			return nil, err
 		}
+	}
+	// the changes of the object not restarting the Pods must not change the hash either,
+	// otherwise any later reconciliation would roll them out
+	hashed = hashed && backstage.IsRestartOnChange(objectKind(obj), ns, ref.Name, restartOnConfigChangeDefault())
 
-		if obj.GetLabels()[model.ExtConfigSyncLabel] == "" || obj.GetAnnotations()[model.BackstageNameAnnotation] == "" ||
-			obj.GetLabels()[model.ExtConfigSyncLabel] != strconv.FormatBool(autoSync) {
+	if err := r.addExtConfig(config, ctx, obj, ref.Name, ref.Key, hashed, ns); err != nil {
+		return nil, err
+	}
+	if ns == backstage.Namespace {
+		return obj, nil
+	}
 
-			obj.GetLabels()[model.ExtConfigSyncLabel] = strconv.FormatBool(autoSync)
-			obj.GetAnnotations()[model.BackstageNameAnnotation] = backstageName
-			if err := r.Update(ctx, obj); err != nil {
-				return err
-			}
-			lg.V(1).Info(fmt.Sprintf("update external config %s with label %s=%s and annotation %s=%s", obj.GetName(), model.ExtConfigSyncLabel, strconv.FormatBool(autoSync), model.BackstageNameAnnotation, backstageName))
+	mirror, err := model.Mirror(obj, backstage)
+	if err != nil {
+		return nil, err
+	}
+	config.AddMirror(mirror)
+	return mirror, nil
+}
+
+// checkReferenceGrant returns an error unless a BackstageReferenceGrant of the namespace allows Backstage CRs of
+// fromNamespace to refer the object of the type (ConfigMap or Secret) with the name
+func (r *BackstageReconciler) checkReferenceGrant(ctx context.Context, fromNamespace string, obj client.Object, namespace, name string) error {
+
+	kind := objectKind(obj)
+	granted, err := referenceGranted(ctx, r.Client, fromNamespace, kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	if !granted {
+		return fmt.Errorf("%s %s/%s can not be referred from namespace %s, not allowed by any BackstageReferenceGrant", kind, namespace, name, fromNamespace)
+	}
+	return nil
+}
+
+// objectKind returns the kind of the external config object, Secret or ConfigMap
+func objectKind(obj client.Object) string {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return "Secret"
+	}
+	return "ConfigMap"
+}
+
+// referenceGranted returns true if a BackstageReferenceGrant of the namespace allows Backstage CRs of
+// fromNamespace to refer the object of the kind with the name
+func referenceGranted(ctx context.Context, reader client.Reader, fromNamespace, kind, namespace, name string) (bool, error) {
+	grants := bs.BackstageReferenceGrantList{}
+	if err := reader.List(ctx, &grants, client.InNamespace(namespace)); err != nil {
+		return false, fmt.Errorf("failed to list reference grants of namespace %s: %w", namespace, err)
+	}
+	for _, grant := range grants.Items {
+		if grant.Allows(fromNamespace, kind, name) {
+			return true, nil
 		}
+	}
+	return false, nil
+}
+
+// addExtConfig reads the external config object and, if hashed, adds its content (or the value of the key, if set) to the config hash
+func (r *BackstageReconciler) addExtConfig(config *model.ExternalConfig, ctx context.Context, obj client.Object, objectName, key string, hashed bool, ns string) error {
 
+	if err := r.Get(ctx, types.NamespacedName{Name: objectName, Namespace: ns}, obj); err != nil {
+		if _, ok := obj.(*corev1.Secret); ok && errors.IsForbidden(err) {
+			return fmt.Errorf("warning: Secrets GET is forbidden, updating Secrets may not cause Pod recreating")
+		}
+		return fmt.Errorf("failed to get external config from %s: %s", objectName, err)
+	}
+	if model.HasLegacyMetadata(obj) {
+		config.LegacyStamped = append(config.LegacyStamped, obj.DeepCopyObject().(client.Object))
+	}
+	if !hashed {
 		return nil
+	}
 
-	})
-	return err
+	if err := config.AddToSyncedConfig(obj, key); err != nil {
+		return fmt.Errorf("failed to add to synced %s: %s", obj.GetName(), err)
+	}
+	return nil
 }
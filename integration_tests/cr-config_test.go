@@ -26,6 +26,9 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
@@ -175,7 +178,7 @@ var _ = When("create backstage with CR configured", func() {
 		}, time.Minute, time.Second).Should(Succeed(), controllerMessage())
 	})
 
-	It("generates label and annotation", func() {
+	It("does not modify referenced ConfigMap", func() {
 
 		appConfig := generateConfigMap(ctx, k8sClient, "app-config1", ns, map[string]string{"key11": "app:", "key12": "app:"}, nil, nil)
 
@@ -192,15 +195,16 @@ var _ = When("create backstage with CR configured", func() {
 		backstageName := createAndReconcileBackstage(ctx, ns, bs, "")
 		Eventually(func(g Gomega) {
 
-			cm := &corev1.ConfigMap{}
-			err := k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: appConfig}, cm)
+			deploy := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)
 			g.Expect(err).ShouldNot(HaveOccurred())
 
-			g.Expect(cm.Labels).To(HaveLen(1))
-			g.Expect(cm.Labels[model.ExtConfigSyncLabel]).To(Equal("true"))
+			cm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: appConfig}, cm)
+			g.Expect(err).ShouldNot(HaveOccurred())
 
-			g.Expect(cm.Annotations).To(HaveLen(1))
-			g.Expect(cm.Annotations[model.BackstageNameAnnotation]).To(Equal(backstageName))
+			g.Expect(cm.Labels).To(BeEmpty())
+			g.Expect(cm.Annotations).To(BeEmpty())
 
 		}, 10*time.Second, time.Second).Should(Succeed())
 
@@ -251,6 +255,104 @@ var _ = When("create backstage with CR configured", func() {
 
 	})
 
+	It("keeps Deployment replicas scaled by another field manager", func() {
+
+		backstageName := createAndReconcileBackstage(ctx, ns, bsv1.BackstageSpec{}, "")
+
+		deploy := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)
+		}, time.Minute, time.Second).Should(Succeed())
+
+		By("scaling the Deployment as HorizontalPodAutoscaler does")
+		patch := client.MergeFrom(deploy.DeepCopy())
+		deploy.Spec.Replicas = ptr.To(int32(3))
+		Expect(k8sClient.Patch(ctx, deploy, patch, client.FieldOwner("horizontal-pod-autoscaler"))).To(Succeed())
+
+		_, err := NewTestBackstageReconciler(ns).ReconcileAny(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: backstageName, Namespace: ns},
+		})
+		Expect(err).To(Not(HaveOccurred()))
+
+		Eventually(func(g Gomega) {
+			bs := &bsv1.Backstage{}
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: backstageName, Namespace: ns}, bs)).To(Succeed())
+			g.Expect(meta.IsStatusConditionTrue(bs.Status.Conditions, string(bsv1.BackstageConditionTypeDeployed))).To(BeTrue())
+
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)).To(Succeed())
+			g.Expect(deploy.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+		}, time.Minute, time.Second).Should(Succeed())
+	})
+
+	It("reverts the changes of Deployment fields configured by the Operator", func() {
+
+		backstageName := createAndReconcileBackstage(ctx, ns, bsv1.BackstageSpec{}, "")
+
+		deploy := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)
+		}, time.Minute, time.Second).Should(Succeed())
+		image := deploy.Spec.Template.Spec.Containers[0].Image
+
+		By("editing the Deployment as kubectl edit does")
+		deploy.Spec.Template.Spec.Containers[0].Image = "quay.io/my-org/edited:1.0.0"
+		Expect(k8sClient.Update(ctx, deploy, client.FieldOwner("kubectl-edit"))).To(Succeed())
+
+		_, err := NewTestBackstageReconciler(ns).ReconcileAny(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: backstageName, Namespace: ns},
+		})
+		Expect(err).To(Not(HaveOccurred()))
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)).To(Succeed())
+			g.Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal(image))
+		}, time.Minute, time.Second).Should(Succeed())
+	})
+
+	It("removes the fields managed by the Operator before switching to server-side apply", func() {
+
+		backstageName := createAndReconcileBackstage(ctx, ns, bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				ExtraEnvs: &bsv1.ExtraEnvs{Envs: []bsv1.Env{{Name: "LEGACY_ENV", Value: "legacy"}}},
+			},
+		}, "")
+
+		deploy := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)
+		}, time.Minute, time.Second).Should(Succeed())
+
+		By("updating the Deployment as the former versions of the Operator did (client-side, with manager field manager)")
+		managedFields := deploy.GetManagedFields()
+		for i := range managedFields {
+			managedFields[i].Manager = "manager"
+			managedFields[i].Operation = metav1.ManagedFieldsOperationUpdate
+		}
+		deploy.SetManagedFields(managedFields)
+		Expect(k8sClient.Update(ctx, deploy, client.FieldOwner("manager"))).To(Succeed())
+
+		By("removing the env var from the CR")
+		update := &bsv1.Backstage{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: backstageName, Namespace: ns}, update)).To(Succeed())
+		update.Spec.Application.ExtraEnvs = nil
+		Expect(k8sClient.Update(ctx, update)).To(Succeed())
+
+		_, err := NewTestBackstageReconciler(ns).ReconcileAny(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: backstageName, Namespace: ns},
+		})
+		Expect(err).To(Not(HaveOccurred()))
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: ns, Name: model.DeploymentName(backstageName)}, deploy)).To(Succeed())
+			for _, env := range deploy.Spec.Template.Spec.Containers[0].Env {
+				g.Expect(env.Name).NotTo(Equal("LEGACY_ENV"))
+			}
+			for _, mf := range deploy.GetManagedFields() {
+				g.Expect(mf.Manager).NotTo(Equal("manager"))
+			}
+		}, time.Minute, time.Second).Should(Succeed())
+	})
+
 	It("creates Backstage deployment with spec.deployment ", func() {
 
 		bs2 := &bsv1.Backstage{}
@@ -30,6 +30,7 @@ import (
 	openshift "github.com/openshift/api/route/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
 	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
@@ -227,7 +228,7 @@ func NewTestBackstageReconciler(namespace string) *TestBackstageReconciler {
 		Scheme:      sch,
 		OwnsRuntime: true,
 		// let's set it explicitly to avoid misunderstanding
-		IsOpenShift: isOpenshift,
+		Platform: model.Platform{IsOpenShift: isOpenshift},
 	}, namespace: namespace}
 }
 
@@ -17,8 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -34,8 +38,11 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
-	backstageiov1alpha1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	backstageiov1alpha1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha1"
+	backstageiov1alpha2 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 	controller "redhat-developer/red-hat-developer-hub-operator/controllers"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/model"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
 	openshift "github.com/openshift/api/route/v1"
 	//+kubebuilder:scaffold:imports
@@ -50,16 +57,26 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(backstageiov1alpha1.AddToScheme(scheme))
+	utilruntime.Must(backstageiov1alpha2.AddToScheme(scheme))
 
 	utilruntime.Must(openshift.Install(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var ownRuntime bool
+	var reportDrift bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -67,6 +84,8 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&ownRuntime, "own-runtime", true, "Making Backstage Controller own runtime objects. "+
 		"If 'true' - all runtime objects created by Controller will be syncing with desired state configured by Controller")
+	flag.BoolVar(&reportDrift, "report-drift", false, "Reporting the drift of runtime objects from the desired state instead of updating them. "+
+		"Takes effect only if 'own-runtime' is 'false'. The drift is reported in the Backstage status and Events")
 
 	opts := zap.Options{
 		Development: true,
@@ -104,21 +123,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	isOpenShift, err := isOpenshift()
+	isOpenShift, err := hasAPIGroup("route.openshift.io")
 	if err != nil {
 		setupLog.Error(err, "unable to detect if a cluster is OpenShift")
 		os.Exit(1)
 	}
 
+	hasGatewayAPI, err := hasAPIGroup("gateway.networking.k8s.io")
+	if err != nil {
+		setupLog.Error(err, "unable to detect if a cluster supports Gateway API")
+		os.Exit(1)
+	}
+
 	if err = (&controller.BackstageReconciler{
 		Client:      mgr.GetClient(),
+		APIReader:   mgr.GetAPIReader(),
 		Scheme:      mgr.GetScheme(),
 		OwnsRuntime: ownRuntime,
-		IsOpenShift: isOpenShift,
+		Platform:    model.Platform{IsOpenShift: isOpenShift, HasGatewayAPI: hasGatewayAPI},
+		ReportDrift: reportDrift,
+		Recorder:    controller.NewEventRecorder(mgr.GetEventRecorderFor("backstage-controller")),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Backstage")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = controller.SetupBackstageWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Backstage")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -130,10 +164,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	if autoSync, ok := os.LookupEnv(controller.AutoSyncEnvVar); ok {
+		setupLog.Info("WARNING: "+controller.AutoSyncEnvVar+" env variable is deprecated, set spec.application.restartOnConfigChange of the Backstage CRs instead",
+			controller.AutoSyncEnvVar, autoSync)
+	}
+
 	setupLog.Info("starting manager with parameters: ",
 		"own-runtime", ownRuntime,
+		"report-drift", reportDrift,
 		"env.LOCALBIN", os.Getenv("LOCALBIN"),
 		"isOpenShift", isOpenShift,
+		"hasGatewayAPI", hasGatewayAPI,
 	)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
@@ -141,8 +182,9 @@ func main() {
 	}
 }
 
-// Automatically detects if the cluster the operator running on is OpenShift
-func isOpenshift() (bool, error) {
+// Automatically detects if the cluster the operator running on serves the API group,
+// such as route.openshift.io (OpenShift) or gateway.networking.k8s.io (Gateway API)
+func hasAPIGroup(name string) (bool, error) {
 	restConfig := ctrl.GetConfigOrDie()
 	dcl, err := discovery.NewDiscoveryClientForConfig(restConfig)
 	if err != nil {
@@ -156,10 +198,68 @@ func isOpenshift() (bool, error) {
 
 	apiGroups := apiList.Groups
 	for i := 0; i < len(apiGroups); i++ {
-		if apiGroups[i].Name == "route.openshift.io" {
+		if apiGroups[i].Name == name {
 			return true, nil
 		}
 	}
 
 	return false, nil
 }
+
+// render prints all the runtime objects the Operator would create for the Backstage CR
+// as a multi-document YAML, with no cluster involved.
+// Usage: manager render --backstage <cr.yaml> [--default-config <dir>] [--config <cm-or-secret.yaml>]... [--openshift] [--gateway-api]
+func render(args []string) error {
+	var backstageFile string
+	var defaultConfigDir string
+	var configFiles stringList
+	var platform model.Platform
+
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.StringVar(&backstageFile, "backstage", "", "Path to the Backstage CR YAML file. Required.")
+	fs.StringVar(&defaultConfigDir, "default-config", "", "Directory containing Operator's default configuration. "+
+		"If not set, $LOCALBIN/default-config is used")
+	fs.Var(&configFiles, "config", "Path to a YAML file with ConfigMap(s) and/or Secret(s) referenced by the Backstage CR. "+
+		"Can be specified multiple times")
+	fs.BoolVar(&platform.IsOpenShift, "openshift", false, "Render objects as for OpenShift cluster (e.g. including Route)")
+	fs.BoolVar(&platform.HasGatewayAPI, "gateway-api", false, "Render objects as for cluster supporting Gateway API (e.g. including HTTPRoute)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if backstageFile == "" {
+		fs.Usage()
+		return fmt.Errorf("--backstage is required")
+	}
+
+	if defaultConfigDir != "" {
+		utils.DefaultConfigDir = defaultConfigDir
+	}
+
+	var manifests []byte
+	for _, f := range append([]string{backstageFile}, configFiles...) {
+		b, err := os.ReadFile(filepath.Clean(f))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		manifests = append(manifests, []byte("\n---\n")...)
+		manifests = append(manifests, b...)
+	}
+
+	objects, err := controller.Render(context.Background(), manifests, platform, scheme)
+	if err != nil {
+		return err
+	}
+	return controller.WriteYaml(os.Stdout, objects)
+}
+
+// stringList is a flag.Value collecting values of repeated flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// InlineAppConfigFile is the key of the inline app-config in the generated ConfigMap
+const InlineAppConfigFile = "app-config-inline.yaml"
+
+type InlineAppConfigFactory struct{}
+
+// factory method to create Inline App Config object
+func (f InlineAppConfigFactory) newBackstageObject() RuntimeObject {
+	return &InlineAppConfig{}
+}
+
+// InlineAppConfig is the ConfigMap generated from spec.application.appConfig.inline.
+// It is mounted and passed to Backstage after the app-config ConfigMaps of the spec (see addAppConfigs)
+type InlineAppConfig struct {
+	ConfigMap *corev1.ConfigMap
+}
+
+func init() {
+	registerConfig("app-config-inline.yaml", InlineAppConfigFactory{})
+}
+
+func InlineAppConfigName(backstageName string) string {
+	return utils.GenerateRuntimeObjectName(backstageName, "backstage-appconfig-inline")
+}
+
+// NewInlineAppConfigMap returns the ConfigMap with the inline app-config of the Backstage spec,
+// or nil if not defined
+func NewInlineAppConfigMap(backstage bsv1.Backstage) (*corev1.ConfigMap, error) {
+	app := backstage.Spec.Application
+	if app == nil || app.AppConfig == nil || app.AppConfig.Inline == nil {
+		return nil, nil
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(app.AppConfig.Inline.Raw, &content); err != nil {
+		return nil, fmt.Errorf("inline app-config has to be an object: %w", err)
+	}
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert inline app-config to yaml: %w", err)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: InlineAppConfigName(backstage.Name), Namespace: backstage.Namespace},
+		Data:       map[string]string{InlineAppConfigFile: string(data)},
+	}, nil
+}
+
+// implementation of RuntimeObject interface
+func (b *InlineAppConfig) Object() client.Object {
+	return b.ConfigMap
+}
+
+// implementation of RuntimeObject interface
+func (b *InlineAppConfig) setObject(obj client.Object) {
+	b.ConfigMap = nil
+	if obj != nil {
+		b.ConfigMap = obj.(*corev1.ConfigMap)
+	}
+}
+
+// implementation of RuntimeObject interface
+func (b *InlineAppConfig) EmptyObject() client.Object {
+	return &corev1.ConfigMap{}
+}
+
+// implementation of RuntimeObject interface
+func (b *InlineAppConfig) addToModel(model *BackstageModel, backstage bsv1.Backstage) (bool, error) {
+	cm, err := NewInlineAppConfigMap(backstage)
+	if err != nil || cm == nil {
+		return false, err
+	}
+	if b.ConfigMap == nil {
+		b.ConfigMap = cm
+	}
+	b.ConfigMap.Data = cm.Data
+
+	model.inlineAppConfig = b
+	model.setRuntimeObject(b)
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+func (b *InlineAppConfig) validate(_ *BackstageModel, _ bsv1.Backstage) error {
+	return nil
+}
+
+func (b *InlineAppConfig) setMetaInfo(backstageName string) {
+	b.ConfigMap.SetName(InlineAppConfigName(backstageName))
+}
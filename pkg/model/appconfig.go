@@ -35,10 +35,14 @@ func (f AppConfigFactory) newBackstageObject() RuntimeObject {
 
 // structure containing ConfigMap where keys are Backstage ConfigApp file names and vaues are contents of the files
 // Mount path is a patch to the follder to place the files to
+// If HotReload, ConfigMap is mounted as a directory (MountPath/ConfigMap name), so the changes are propagated to the Pod
+// FileOrder lists the files passed to Backstage first, the others follow sorted by name
 type AppConfig struct {
 	ConfigMap *corev1.ConfigMap
 	MountPath string
 	Key       string
+	HotReload bool
+	FileOrder []string
 }
 
 func init() {
@@ -51,20 +55,32 @@ func AppConfigDefaultName(backstageName string) string {
 
 func addAppConfigs(spec bsv1.BackstageSpec, deployment *appsv1.Deployment, model *BackstageModel) {
 
-	if spec.Application == nil || spec.Application.AppConfig == nil || spec.Application.AppConfig.ConfigMaps == nil {
+	if spec.Application == nil || spec.Application.AppConfig == nil {
 		return
 	}
 
+	mp := defaultMountDir
+	if spec.Application.AppConfig.MountPath != "" {
+		mp = spec.Application.AppConfig.MountPath
+	}
 	for _, configMap := range spec.Application.AppConfig.ConfigMaps {
-		cm := model.ExternalConfig.AppConfigs[configMap.Name]
-		mp := defaultMountDir
-		if spec.Application.AppConfig.MountPath != "" {
-			mp = spec.Application.AppConfig.MountPath
-		}
+		cm := model.ExternalConfig.AppConfigs[ExternalConfigKey(configMap)]
 		ac := AppConfig{
 			ConfigMap: &cm,
 			MountPath: mp,
 			Key:       configMap.Key,
+			HotReload: spec.Application.AppConfig.HotReload,
+			FileOrder: spec.Application.AppConfig.FileOrder,
+		}
+		ac.updatePod(deployment)
+	}
+
+	// inline app-config goes last, overriding the ConfigMaps
+	if model.inlineAppConfig != nil {
+		ac := AppConfig{
+			ConfigMap: model.inlineAppConfig.ConfigMap,
+			MountPath: mp,
+			HotReload: spec.Application.AppConfig.HotReload,
 		}
 		ac.updatePod(deployment)
 	}
@@ -110,14 +126,44 @@ func (b *AppConfig) setMetaInfo(backstageName string) {
 // it contrubutes to Volumes, container.VolumeMounts and contaiter.Args
 func (b *AppConfig) updatePod(deployment *appsv1.Deployment) {
 
-	utils.MountFilesFrom(&deployment.Spec.Template.Spec, &deployment.Spec.Template.Spec.Containers[0], utils.ConfigMapObjectKind,
-		b.ConfigMap.Name, b.MountPath, b.Key, b.ConfigMap.Data)
-
 	fileDir := b.MountPath
-	for file := range b.ConfigMap.Data {
-		if b.Key == "" || b.Key == file {
-			deployment.Spec.Template.Spec.Containers[0].Args =
-				append(deployment.Spec.Template.Spec.Containers[0].Args, []string{"--config", filepath.Join(fileDir, file)}...)
+	if b.HotReload {
+		// no data => mounted as directory without SubPath, which kubelet updates
+		utils.MountFilesFrom(&deployment.Spec.Template.Spec, &deployment.Spec.Template.Spec.Containers[0], utils.ConfigMapObjectKind,
+			b.ConfigMap.Name, b.MountPath, "", nil)
+		fileDir = filepath.Join(b.MountPath, b.ConfigMap.Name)
+	} else {
+		utils.MountFilesFrom(&deployment.Spec.Template.Spec, &deployment.Spec.Template.Spec.Containers[0], utils.ConfigMapObjectKind,
+			b.ConfigMap.Name, b.MountPath, b.Key, b.ConfigMap.Data)
+	}
+
+	for _, file := range b.files() {
+		deployment.Spec.Template.Spec.Containers[0].Args =
+			append(deployment.Spec.Template.Spec.Containers[0].Args, []string{"--config", filepath.Join(fileDir, file)}...)
+	}
+}
+
+// files returns the app-config files (ConfigMap keys) in the order they are passed to Backstage:
+// the Key only if specified, otherwise the ones listed in FileOrder first, then the rest sorted by name
+func (b *AppConfig) files() []string {
+	if b.Key != "" {
+		if _, ok := b.ConfigMap.Data[b.Key]; ok {
+			return []string{b.Key}
+		}
+		return nil
+	}
+	var files []string
+	ordered := map[string]bool{}
+	for _, file := range b.FileOrder {
+		if _, ok := b.ConfigMap.Data[file]; ok && !ordered[file] {
+			files = append(files, file)
+			ordered[file] = true
+		}
+	}
+	for _, file := range utils.SortedKeys(b.ConfigMap.Data) {
+		if !ordered[file] {
+			files = append(files, file)
 		}
 	}
+	return files
 }
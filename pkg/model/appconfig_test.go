@@ -22,6 +22,7 @@ import (
 	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
 	"testing"
 
@@ -77,7 +78,7 @@ func TestDefaultAppConfig(t *testing.T) {
 
 	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("app-config.yaml", "raw-app-config.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.True(t, len(model.RuntimeObjects) > 0)
@@ -107,7 +108,7 @@ func TestSpecifiedAppConfig(t *testing.T) {
 	testObj.externalConfig.AppConfigs = map[string]corev1.ConfigMap{appConfigTestCm.Name: appConfigTestCm, appConfigTestCm2.Name: appConfigTestCm2,
 		appConfigTestCm3.Name: appConfigTestCm3}
 	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig,
-		true, false, testObj.scheme)
+		true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.True(t, len(model.RuntimeObjects) > 0)
@@ -134,7 +135,7 @@ func TestDefaultAndSpecifiedAppConfig(t *testing.T) {
 	//testObj.detailedSpec.AddConfigObject(&AppConfig{ConfigMap: &cm, MountPath: "/my/path"})
 	testObj.externalConfig.AppConfigs[appConfigTestCm.Name] = appConfigTestCm
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.True(t, len(model.RuntimeObjects) > 0)
@@ -151,3 +152,94 @@ func TestDefaultAndSpecifiedAppConfig(t *testing.T) {
 		deployment.deployment.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name)
 
 }
+
+func TestHotReloadAppConfig(t *testing.T) {
+
+	bs := *appConfigTestBackstage.DeepCopy()
+	bs.Spec.Application.AppConfig.HotReload = true
+	bs.Spec.Application.AppConfig.ConfigMaps = []bsv1.ObjectKeyRef{{Name: appConfigTestCm2.Name},
+		{Name: appConfigTestCm3.Name, Key: "conf31.yaml"}}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+	testObj.externalConfig.AppConfigs = map[string]corev1.ConfigMap{appConfigTestCm2.Name: appConfigTestCm2,
+		appConfigTestCm3.Name: appConfigTestCm3}
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	container := model.backstageDeployment.container()
+	// directory per ConfigMap, no SubPath
+	assert.Equal(t, 2, len(container.VolumeMounts))
+	for _, vm := range container.VolumeMounts {
+		assert.Empty(t, vm.SubPath)
+	}
+	assert.Equal(t, "/my/path/app-config2", container.VolumeMounts[0].MountPath)
+	assert.Equal(t, "/my/path/app-config3", container.VolumeMounts[1].MountPath)
+
+	assert.Equal(t, 6, len(container.Args))
+	assert.Contains(t, container.Args, "/my/path/app-config2/conf21.yaml")
+	assert.Contains(t, container.Args, "/my/path/app-config3/conf31.yaml")
+	assert.NotContains(t, container.Args, "/my/path/app-config3/conf32.yaml")
+}
+
+func TestAppConfigOrder(t *testing.T) {
+
+	bs := *appConfigTestBackstage.DeepCopy()
+	bs.Spec.Application.AppConfig.FileOrder = []string{"conf32.yaml", "unknown.yaml"}
+	bs.Spec.Application.AppConfig.ConfigMaps = []bsv1.ObjectKeyRef{{Name: appConfigTestCm3.Name}, {Name: appConfigTestCm2.Name}}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("app-config.yaml", "raw-app-config.yaml")
+	testObj.externalConfig.AppConfigs = map[string]corev1.ConfigMap{appConfigTestCm2.Name: appConfigTestCm2,
+		appConfigTestCm3.Name: appConfigTestCm3}
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	container := model.backstageDeployment.container()
+	// default app-config first, then ConfigMaps in the spec order, files listed in fileOrder first, then the sorted rest
+	assert.Equal(t, []string{
+		"--config", "/opt/app-root/src/default.app-config.yaml",
+		"--config", "/my/path/conf32.yaml",
+		"--config", "/my/path/conf31.yaml",
+		"--config", "/my/path/conf21.yaml",
+		"--config", "/my/path/conf22.yaml",
+	}, container.Args)
+
+	// volume mounts are deterministic as well
+	var mounts []string
+	for _, vm := range container.VolumeMounts {
+		mounts = append(mounts, vm.SubPath)
+	}
+	assert.Equal(t, []string{"default.app-config.yaml", "conf31.yaml", "conf32.yaml", "conf21.yaml", "conf22.yaml"}, mounts)
+}
+
+func TestInlineAppConfig(t *testing.T) {
+
+	bs := *appConfigTestBackstage.DeepCopy()
+	bs.Spec.Application.AppConfig.ConfigMaps = []bsv1.ObjectKeyRef{{Name: appConfigTestCm.Name}}
+	bs.Spec.Application.AppConfig.Inline = &apiextensionsv1.JSON{Raw: []byte(`{"app":{"title":"My Backstage"},"backend":{"baseUrl":"http://localhost:7007"}}`)}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("app-config.yaml", "raw-app-config.yaml")
+	testObj.externalConfig.AppConfigs[appConfigTestCm.Name] = appConfigTestCm
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	assert.NotNil(t, model.inlineAppConfig)
+	cm := model.inlineAppConfig.ConfigMap
+	assert.Equal(t, InlineAppConfigName(bs.Name), cm.Name)
+	assert.Equal(t, "ns123", cm.Namespace)
+	assert.Equal(t, "app:\n  title: My Backstage\nbackend:\n  baseUrl: http://localhost:7007\n", cm.Data[InlineAppConfigFile])
+
+	// default, specified ConfigMap, inline
+	container := model.backstageDeployment.container()
+	assert.Equal(t, 6, len(container.Args))
+	assert.Equal(t, "/my/path/conf.yaml", container.Args[3])
+	assert.Equal(t, "/my/path/"+InlineAppConfigFile, container.Args[5])
+	assert.Equal(t, 3, len(container.VolumeMounts))
+	assert.Equal(t, utils.GenerateVolumeNameFromCmOrSecret(cm.Name), container.VolumeMounts[2].Name)
+
+	// not an object
+	bs.Spec.Application.AppConfig.Inline = &apiextensionsv1.JSON{Raw: []byte(`"app: title"`)}
+	_, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.ErrorContains(t, err, "inline app-config has to be an object")
+	assert.Len(t, ValidateSpec(bs.Spec, NewExternalConfig()), 1)
+}
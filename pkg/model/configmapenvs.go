@@ -45,7 +45,7 @@ func addConfigMapEnvs(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment
 	}
 
 	for _, configMap := range spec.Application.ExtraEnvs.ConfigMaps {
-		cm := model.ExternalConfig.ExtraEnvConfigMaps[configMap.Name]
+		cm := model.ExternalConfig.ExtraEnvConfigMaps[ExternalConfigKey(configMap)]
 		cmf := ConfigMapEnvs{
 			ConfigMap: &cm,
 			Key:       configMap.Key,
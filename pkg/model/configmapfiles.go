@@ -51,7 +51,7 @@ func addConfigMapFiles(spec v1alpha2.BackstageSpec, deployment *appsv1.Deploymen
 	}
 
 	for _, configMap := range spec.Application.ExtraFiles.ConfigMaps {
-		cm := model.ExternalConfig.ExtraFileConfigMaps[configMap.Name]
+		cm := model.ExternalConfig.ExtraFileConfigMaps[ExternalConfigKey(configMap)]
 		cmf := ConfigMapFiles{
 			ConfigMap: &cm,
 			MountPath: mp,
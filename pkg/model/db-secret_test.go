@@ -47,7 +47,7 @@ func TestEmptyDbSecret(t *testing.T) {
 	// expected generatePassword = false (default db-secret defined) will come from preprocess
 	testObj := createBackstageTest(bs).withDefaultConfig(true).withLocalDb().addToDefaultConfig("db-secret.yaml", "db-empty-secret.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, model.LocalDbSecret)
@@ -66,7 +66,7 @@ func TestDefaultWithGeneratedSecrets(t *testing.T) {
 	// expected generatePassword = true (no db-secret defined) will come from preprocess
 	testObj := createBackstageTest(bs).withDefaultConfig(true).withLocalDb().addToDefaultConfig("db-secret.yaml", "db-generated-secret.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.Equal(t, fmt.Sprintf("backstage-psql-secret-%s", bs.Name), model.LocalDbSecret.secret.Name)
@@ -87,7 +87,7 @@ func TestSpecifiedSecret(t *testing.T) {
 	// expected generatePassword = false (db-secret defined in the spec) will come from preprocess
 	testObj := createBackstageTest(bs).withDefaultConfig(true).withLocalDb().addToDefaultConfig("db-secret.yaml", "db-generated-secret.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.Nil(t, model.LocalDbSecret)
@@ -46,7 +46,7 @@ func TestDefault(t *testing.T) {
 	bs := *dbStatefulSetBackstage.DeepCopy()
 	testObj := createBackstageTest(bs).withDefaultConfig(true)
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, model.LocalDbService.service.Name, model.localDbStatefulSet.statefulSet.Spec.ServiceName)
@@ -64,7 +64,7 @@ func TestOverrideDbImage(t *testing.T) {
 
 	_ = os.Setenv(LocalDbImageEnvVar, "dummy")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "dummy", model.localDbStatefulSet.statefulSet.Spec.Template.Spec.Containers[0].Image)
@@ -76,7 +76,7 @@ func TestImagePullSecretSpec(t *testing.T) {
 	bs.Spec.Application.ImagePullSecrets = []string{"my-secret1", "my-secret2"}
 
 	testObj := createBackstageTest(bs).withDefaultConfig(true)
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 2, len(model.localDbStatefulSet.statefulSet.Spec.Template.Spec.ImagePullSecrets))
@@ -88,7 +88,7 @@ func TestImagePullSecretSpec(t *testing.T) {
 	testObj = createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("db-statefulset.yaml", "ips-deployment.yaml")
 
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	// if imagepullsecrets not defined - default used
@@ -103,7 +103,7 @@ func TestImagePullSecretSpec(t *testing.T) {
 	testObj = createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("db-statefulset.yaml", "ips-deployment.yaml")
 
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 0, len(model.localDbStatefulSet.statefulSet.Spec.Template.Spec.ImagePullSecrets))
@@ -116,16 +116,22 @@ func (b *BackstageDeployment) validate(model *BackstageModel, backstage bsv1.Bac
 
 	addConfigMapEnvs(backstage.Spec, b.deployment, model)
 
-	if err := addSecretFiles(backstage.Spec, b.deployment); err != nil {
+	if err := addSecretFiles(backstage.Spec, b.deployment, model); err != nil {
 		return err
 	}
 
-	if err := addSecretEnvs(backstage.Spec, b.deployment); err != nil {
+	if err := addSecretEnvs(backstage.Spec, b.deployment, model); err != nil {
 		return err
 	}
 	if err := addDynamicPlugins(backstage.Spec, b.deployment, model); err != nil {
 		return err
 	}
+	if err := addDynamicPluginsRegistry(backstage.Spec, b.deployment, model); err != nil {
+		return err
+	}
+	if err := addDynamicPluginsCache(b.deployment, model); err != nil {
+		return err
+	}
 
 	//DbSecret
 	if backstage.Spec.IsAuthSecretSpecified() {
@@ -154,6 +160,7 @@ func (b *BackstageDeployment) podSpec() *corev1.PodSpec {
 func (b *BackstageDeployment) setDeployment(backstage bsv1.Backstage) error {
 
 	// set from backstage.Spec.Application
+	// replicas not set by the CR nor the default or raw configuration are left to others (e.g. HorizontalPodAutoscaler)
 	if backstage.Spec.Application != nil {
 		b.setReplicas(backstage.Spec.Application.Replicas)
 		utils.SetImagePullSecrets(b.podSpec(), backstage.Spec.Application.ImagePullSecrets)
@@ -16,6 +16,8 @@ package model
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -23,6 +25,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -51,7 +54,7 @@ func TestSpecs(t *testing.T) {
 	testObj := createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "my-image:1.0.0", model.backstageDeployment.container().Image)
@@ -61,6 +64,42 @@ func TestSpecs(t *testing.T) {
 
 }
 
+func TestReplicasNotManagedIfNotSpecified(t *testing.T) {
+	bs := *deploymentTestBackstage.DeepCopy()
+
+	// the shipped default configuration does not set replicas
+	utils.DefaultConfigDir = "../../config/manager/default-config"
+	defer func() { utils.DefaultConfigDir = "" }()
+	testObj := createBackstageTest(bs)
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
+	assert.NoError(t, err)
+
+	// not in the apply configuration, so left to others (e.g. HorizontalPodAutoscaler)
+	assert.Nil(t, model.backstageDeployment.deployment.Spec.Replicas)
+	data, err := json.Marshal(model.backstageDeployment.deployment)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "replicas")
+}
+
+func TestReplicasOfRawConfig(t *testing.T) {
+	bs := *deploymentTestBackstage.DeepCopy()
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true).
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+	testObj.externalConfig.RawConfig["deployment.yaml"] = strings.Replace(testObj.externalConfig.RawConfig["deployment.yaml"], "  replicas: 1\n", "  replicas: 2\n", 1)
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To(int32(2)), model.backstageDeployment.deployment.Spec.Replicas)
+
+	// overridden by the CR
+	bs.Spec.Application = &bsv1.Application{Replicas: ptr.To(int32(3))}
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To(int32(3)), model.backstageDeployment.deployment.Spec.Replicas)
+}
+
 // It tests the overriding image feature
 func TestOverrideBackstageImage(t *testing.T) {
 
@@ -71,7 +110,7 @@ func TestOverrideBackstageImage(t *testing.T) {
 
 	t.Setenv(BackstageImageEnvVar, "dummy")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 2, len(model.backstageDeployment.podSpec().Containers))
@@ -87,7 +126,7 @@ func TestSpecImagePullSecrets(t *testing.T) {
 	testObj := createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("deployment.yaml", "ips-deployment.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	// if imagepullsecrets not defined - default used
@@ -99,7 +138,7 @@ func TestSpecImagePullSecrets(t *testing.T) {
 	testObj = createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("deployment.yaml", "ips-deployment.yaml")
 
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	// if explicitly set empty slice - they are empty
@@ -143,7 +182,7 @@ spec:
 	testObj := createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	// label added
@@ -190,7 +229,7 @@ spec:
 	testObj := createBackstageTest(bs).withDefaultConfig(true).
 		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "backstage-backend", model.backstageDeployment.container().Name)
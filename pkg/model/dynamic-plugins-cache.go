@@ -0,0 +1,315 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the volume the install-dynamic-plugins init container installs the plugins into, shared with Backstage container
+const DynamicPluginsRootVolume = "dynamic-plugins-root"
+
+// the whole cache is mounted to the init container there, to garbage collect the stale entries
+const dynamicPluginsCacheMountPath = "/dynamic-plugins-cache"
+
+// the volume of the ConfigMap listing the cache entries in use (see DynamicPluginsCacheKeys), mounted to the init container
+const dynamicPluginsCacheKeysVolume = "dynamic-plugins-cache-keys"
+const dynamicPluginsCacheKeysMountPath = "/dynamic-plugins-cache-keys"
+const dynamicPluginsCacheKeysFile = "keys"
+
+// the directory of the cache the init container installs the plugins into (per pod, see dynamicPluginsCacheScript)
+const dynamicPluginsCacheInstallPath = ".installing/$(DYNAMIC_PLUGINS_CACHE_POD)"
+
+var defaultDynamicPluginsCacheSize = resource.MustParse("5Gi")
+
+// dynamicPluginsCacheScript wraps the install-dynamic-plugins command (passed as arguments).
+// The pods sharing the cache are serialized with the lock of the cache. Unless the entry of the current configuration
+// (DYNAMIC_PLUGINS_CACHE_KEY directory) is complete, the plugins are installed into the directory of the pod
+// and moved into place once complete, so the entry is never seen partially installed.
+// Then the entries not listed in DYNAMIC_PLUGINS_CACHE_KEYS (used by the live ReplicaSets) are removed,
+// as well as the installation directories left by the pods deleted while installing
+const dynamicPluginsCacheScript = `set -e
+cache="${DYNAMIC_PLUGINS_CACHE}"
+key="${DYNAMIC_PLUGINS_CACHE_KEY}"
+tmp="$cache/.installing/${DYNAMIC_PLUGINS_CACHE_POD}"
+exec 9>"$cache/.lock"
+flock 9
+if [ -f "$cache/$key/.installed" ]; then
+  echo "dynamic plugins $key are installed already"
+  rm -rf "$tmp"
+else
+  rm -rf "$tmp"/* "$tmp"/.[!.]* "$tmp"/..?*
+  "$@"
+  touch "$tmp/.installed"
+  rm -rf "$cache/$key"
+  mv "$tmp" "$cache/$key"
+fi
+if [ -f "${DYNAMIC_PLUGINS_CACHE_KEYS}" ]; then
+  for entry in "$cache"/*; do
+    name=$(basename "$entry")
+    if [ "$name" != "$key" ] && [ "$name" != "lost+found" ] && ! grep -qxF "$name" "${DYNAMIC_PLUGINS_CACHE_KEYS}"; then
+      echo "removing stale dynamic plugins $name"
+      rm -rf "$entry"
+    fi
+  done
+fi
+find "$cache/.installing" -mindepth 1 -maxdepth 1 -mmin +1440 -exec rm -rf {} + 2>/dev/null || true
+`
+
+type DynamicPluginsCacheFactory struct{}
+
+func (f DynamicPluginsCacheFactory) newBackstageObject() RuntimeObject {
+	return &DynamicPluginsCache{}
+}
+
+// DynamicPluginsCache is the PersistentVolumeClaim the dynamic plugins are installed into
+// if spec.application.dynamicPluginsCache is set. It replaces the dynamic-plugins-root volume of the pod,
+// which is mounted with subPath of the cache key (see dynamicPluginsCacheKey)
+type DynamicPluginsCache struct {
+	pvc  *corev1.PersistentVolumeClaim
+	keys *DynamicPluginsCacheKeys
+}
+
+func init() {
+	registerConfig("dynamic-plugins-cache.yaml", DynamicPluginsCacheFactory{})
+}
+
+func DynamicPluginsCacheName(backstageName string) string {
+	return utils.GenerateRuntimeObjectName(backstageName, "backstage-dynamic-plugins-cache")
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) Object() client.Object {
+	return c.pvc
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) setObject(obj client.Object) {
+	c.pvc = nil
+	if obj != nil {
+		c.pvc = obj.(*corev1.PersistentVolumeClaim)
+	}
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) EmptyObject() client.Object {
+	return &corev1.PersistentVolumeClaim{}
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) addToModel(model *BackstageModel, backstage bsv1.Backstage) (bool, error) {
+	if backstage.Spec.Application == nil || backstage.Spec.Application.DynamicPluginsCache == nil {
+		return false, nil
+	}
+	spec := backstage.Spec.Application.DynamicPluginsCache
+
+	if c.pvc == nil {
+		c.pvc = &corev1.PersistentVolumeClaim{}
+	}
+	if len(spec.AccessModes) > 0 {
+		c.pvc.Spec.AccessModes = spec.AccessModes
+	} else if len(c.pvc.Spec.AccessModes) == 0 {
+		c.pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	if spec.StorageClassName != nil {
+		c.pvc.Spec.StorageClassName = spec.StorageClassName
+	}
+	if c.pvc.Spec.Resources.Requests == nil {
+		c.pvc.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	if spec.Size != nil {
+		c.pvc.Spec.Resources.Requests[corev1.ResourceStorage] = *spec.Size
+	} else if _, ok := c.pvc.Spec.Resources.Requests[corev1.ResourceStorage]; !ok {
+		c.pvc.Spec.Resources.Requests[corev1.ResourceStorage] = defaultDynamicPluginsCacheSize
+	}
+
+	model.dynamicPluginsCache = c
+	model.setRuntimeObject(c)
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) validate(_ *BackstageModel, _ bsv1.Backstage) error {
+	return nil
+}
+
+// addDynamicPluginsCache mounts the cache, if configured, to the pod in place of the dynamic-plugins-root volume
+// and makes the init container install the plugins only if not cached.
+// Called once the pod and the dynamic plugins configuration are complete, as the cache key depends on them
+func addDynamicPluginsCache(deployment *appsv1.Deployment, model *BackstageModel) error {
+
+	c := model.dynamicPluginsCache
+	if c == nil {
+		return nil
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	i, _ := DynamicPluginsInitContainer(podSpec.InitContainers)
+	if i < 0 {
+		return fmt.Errorf("dynamic plugins cache configured but no InitContainer %s defined", DynamicPluginsInitContainerName)
+	}
+	initContainer := &podSpec.InitContainers[i]
+
+	found := false
+	for v := range podSpec.Volumes {
+		if podSpec.Volumes[v].Name == DynamicPluginsRootVolume {
+			podSpec.Volumes[v].VolumeSource = corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: c.pvc.Name},
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("dynamic plugins cache configured but no %s volume defined", DynamicPluginsRootVolume)
+	}
+
+	key := dynamicPluginsCacheKey(model, initContainer)
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for ci := range containers {
+			for vi := range containers[ci].VolumeMounts {
+				if containers[ci].VolumeMounts[vi].Name == DynamicPluginsRootVolume {
+					containers[ci].VolumeMounts[vi].SubPath = key
+				}
+			}
+		}
+	}
+	// the plugins are installed into the directory of the pod, moved to the key directory once complete
+	for vi := range initContainer.VolumeMounts {
+		if initContainer.VolumeMounts[vi].Name == DynamicPluginsRootVolume {
+			initContainer.VolumeMounts[vi].SubPath = ""
+			initContainer.VolumeMounts[vi].SubPathExpr = dynamicPluginsCacheInstallPath
+		}
+	}
+
+	c.keys = &DynamicPluginsCacheKeys{configMap: &corev1.ConfigMap{
+		Data: map[string]string{dynamicPluginsCacheKeysFile: dynamicPluginsCacheKeysContent(model.ExternalConfig.DynamicPluginsCacheKeys, key)},
+	}}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: dynamicPluginsCacheKeysVolume, VolumeSource: corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: c.pvc.Name},
+			// until the ConfigMap is created, no entries are removed
+			Optional: ptr.To(true),
+		},
+	}})
+
+	initContainer.VolumeMounts = append(initContainer.VolumeMounts,
+		corev1.VolumeMount{Name: DynamicPluginsRootVolume, MountPath: dynamicPluginsCacheMountPath},
+		corev1.VolumeMount{Name: dynamicPluginsCacheKeysVolume, MountPath: dynamicPluginsCacheKeysMountPath, ReadOnly: true})
+	initContainer.Env = append(initContainer.Env,
+		corev1.EnvVar{Name: "DYNAMIC_PLUGINS_CACHE", Value: dynamicPluginsCacheMountPath},
+		corev1.EnvVar{Name: "DYNAMIC_PLUGINS_CACHE_KEY", Value: key},
+		corev1.EnvVar{Name: "DYNAMIC_PLUGINS_CACHE_KEYS", Value: path.Join(dynamicPluginsCacheKeysMountPath, dynamicPluginsCacheKeysFile)},
+		corev1.EnvVar{Name: "DYNAMIC_PLUGINS_CACHE_POD", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		}})
+	initContainer.Command = append([]string{"sh", "-c", dynamicPluginsCacheScript, DynamicPluginsInitContainerName},
+		append(initContainer.Command, initContainer.Args...)...)
+	initContainer.Args = nil
+
+	return nil
+}
+
+// implementation of RuntimeObject interface
+func (c *DynamicPluginsCache) setMetaInfo(backstageName string) {
+	c.pvc.SetName(DynamicPluginsCacheName(backstageName))
+}
+
+// dynamicPluginsCacheKey returns the hash of what the installed plugins depend on:
+// the effective dynamic-plugins.yaml and the init container image
+func dynamicPluginsCacheKey(model *BackstageModel, initContainer *corev1.Container) string {
+	content := model.ExternalConfig.DynamicPlugins.Data[DynamicPluginsFile]
+	if model.dynamicPlugins != nil {
+		content = model.dynamicPlugins.ConfigMap.Data[DynamicPluginsFile]
+	}
+	h := sha256.New()
+	h.Write([]byte(initContainer.Image))
+	h.Write([]byte{0})
+	h.Write([]byte(content))
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// DynamicPluginsCacheKey returns the cache entry (see dynamicPluginsCacheKey) the containers of the pod use, if any
+func DynamicPluginsCacheKey(podSpec corev1.PodSpec) string {
+	for _, c := range podSpec.Containers {
+		for _, vm := range c.VolumeMounts {
+			if vm.Name == DynamicPluginsRootVolume && vm.SubPath != "" {
+				return vm.SubPath
+			}
+		}
+	}
+	return ""
+}
+
+// dynamicPluginsCacheKeysContent returns the sorted list of the cache entries in use, one per line
+func dynamicPluginsCacheKeysContent(live []string, key string) string {
+	return strings.Join(sets.List(sets.New(live...).Insert(key)), "\n") + "\n"
+}
+
+// DynamicPluginsCacheKeys is the ConfigMap listing the entries of the dynamic plugins cache in use: the current one
+// and the ones of the live ReplicaSets (see ExternalConfig.DynamicPluginsCacheKeys). The init container
+// removes the entries not listed. It is not configured with default or raw config, but added to the model by InitObjects
+// once the Deployment is complete, as the current entry depends on it
+type DynamicPluginsCacheKeys struct {
+	configMap *corev1.ConfigMap
+}
+
+// implementation of RuntimeObject interface
+func (k *DynamicPluginsCacheKeys) Object() client.Object {
+	return k.configMap
+}
+
+// implementation of RuntimeObject interface
+func (k *DynamicPluginsCacheKeys) setObject(obj client.Object) {
+	k.configMap = nil
+	if obj != nil {
+		k.configMap = obj.(*corev1.ConfigMap)
+	}
+}
+
+// implementation of RuntimeObject interface
+func (k *DynamicPluginsCacheKeys) EmptyObject() client.Object {
+	return &corev1.ConfigMap{}
+}
+
+// implementation of RuntimeObject interface
+func (k *DynamicPluginsCacheKeys) addToModel(model *BackstageModel, _ bsv1.Backstage) (bool, error) {
+	model.RuntimeObjects = append(model.RuntimeObjects, k)
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+func (k *DynamicPluginsCacheKeys) validate(_ *BackstageModel, _ bsv1.Backstage) error {
+	return nil
+}
+
+// implementation of RuntimeObject interface
+// named as the cache PersistentVolumeClaim
+func (k *DynamicPluginsCacheKeys) setMetaInfo(backstageName string) {
+	k.configMap.SetName(DynamicPluginsCacheName(backstageName))
+}
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"testing"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+)
+
+func TestDynamicPluginsCache(t *testing.T) {
+
+	bs := testDynamicPluginsBackstage.DeepCopy()
+	bs.Spec.Application.DynamicPluginsCache = &bsv1.DynamicPluginsCache{StorageClassName: ptr.To("fast")}
+	bs.Spec.Application.DynamicPlugins = []bsv1.DynamicPlugin{{Package: "./dynamic-plugins/dist/plugin-a"}}
+
+	testObj := createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	pvc := model.dynamicPluginsCache.pvc
+	assert.Equal(t, DynamicPluginsCacheName(bs.Name), pvc.Name)
+	assert.Equal(t, "fast", *pvc.Spec.StorageClassName)
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, pvc.Spec.AccessModes)
+	assert.Equal(t, resource.MustParse("5Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+
+	podSpec := model.backstageDeployment.deployment.Spec.Template.Spec
+	for _, v := range podSpec.Volumes {
+		if v.Name == DynamicPluginsRootVolume {
+			assert.Nil(t, v.Ephemeral)
+			assert.Equal(t, pvc.Name, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+
+	ic := initContainer(model)
+	key := ""
+	for _, e := range ic.Env {
+		if e.Name == "DYNAMIC_PLUGINS_CACHE_KEY" {
+			key = e.Value
+		}
+	}
+	assert.NotEmpty(t, key)
+	// the plugins installed into the directory of the pod, mounted to the init container, the key subPath
+	// mounted to Backstage container, the whole cache and the keys in use to the init container
+	assert.Equal(t, corev1.VolumeMount{Name: DynamicPluginsRootVolume, MountPath: "/dynamic-plugins-root", SubPathExpr: dynamicPluginsCacheInstallPath}, ic.VolumeMounts[0])
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: DynamicPluginsRootVolume, MountPath: dynamicPluginsCacheMountPath},
+		{Name: dynamicPluginsCacheKeysVolume, MountPath: dynamicPluginsCacheKeysMountPath, ReadOnly: true},
+	}, ic.VolumeMounts[len(ic.VolumeMounts)-2:])
+	assert.Contains(t, ic.Env, corev1.EnvVar{Name: "DYNAMIC_PLUGINS_CACHE_POD", ValueFrom: &corev1.EnvVarSource{
+		FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}})
+	assert.Equal(t, key, model.backstageDeployment.container().VolumeMounts[0].SubPath)
+	assert.Equal(t, key, DynamicPluginsCacheKey(podSpec))
+	// install command wrapped
+	assert.Equal(t, []string{"sh", "-c", dynamicPluginsCacheScript, DynamicPluginsInitContainerName,
+		"./install-dynamic-plugins.sh", "/dynamic-plugins-root"}, ic.Command)
+
+	// the keys in use are applied along with the other runtime objects
+	keys := model.dynamicPluginsCache.keys.configMap
+	assert.Equal(t, pvc.Name, keys.Name)
+	assert.Equal(t, key+"\n", keys.Data[dynamicPluginsCacheKeysFile])
+	assert.Contains(t, model.RuntimeObjects, RuntimeObject(model.dynamicPluginsCache.keys))
+
+	// the same configuration, the same key, the entries of the live ReplicaSets are kept
+	testObj.externalConfig.DynamicPluginsCacheKeys = []string{"old", key}
+	model, err = InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Equal(t, key, model.backstageDeployment.container().VolumeMounts[0].SubPath)
+	assert.Equal(t, key+"\nold\n", model.dynamicPluginsCache.keys.configMap.Data[dynamicPluginsCacheKeysFile])
+
+	// changed plugins, another key
+	bs.Spec.Application.DynamicPlugins[0].Disabled = ptr.To(true)
+	model, err = InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, model.backstageDeployment.container().VolumeMounts[0].SubPath)
+}
+
+func TestDynamicPluginsCacheDisabled(t *testing.T) {
+
+	bs := testDynamicPluginsBackstage.DeepCopy()
+
+	testObj := createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.dynamicPluginsCache)
+	assert.Empty(t, DynamicPluginsCacheKey(model.backstageDeployment.deployment.Spec.Template.Spec))
+	assert.Empty(t, initContainer(model).VolumeMounts[0].SubPath)
+	assert.Equal(t, []string{"./install-dynamic-plugins.sh", "/dynamic-plugins-root"}, initContainer(model).Command)
+}
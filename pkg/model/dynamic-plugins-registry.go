@@ -0,0 +1,198 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Default keys of the objects referred in spec.application.dynamicPluginsRegistry
+const (
+	DynamicPluginsNpmrcKey    = ".npmrc"
+	DynamicPluginsCABundleKey = "ca-bundle.crt"
+	DynamicPluginsOCIAuthKey  = ".dockerconfigjson"
+)
+
+const (
+	// the volume of the default configuration, mounted to the init container with .npmrc subPath
+	dynamicPluginsNpmrcVolume = "dynamic-plugins-npmrc"
+	dynamicPluginsNpmrcPath   = "/opt/app-root/src/.npmrc.dynamic-plugins"
+
+	dynamicPluginsCABundleVolume = "dynamic-plugins-ca-bundle"
+	dynamicPluginsCABundleDir    = "/opt/app-root/src/dynamic-plugins-ca"
+
+	dynamicPluginsOCIAuthVolume = "dynamic-plugins-registry-auth"
+	dynamicPluginsOCIAuthDir    = "/opt/app-root/src/dynamic-plugins-registry-auth"
+	dynamicPluginsOCIAuthFile   = "auth.json"
+)
+
+// addDynamicPluginsRegistry mounts the npm configuration, the CA bundle and the OCI registry credentials
+// referred in spec.application.dynamicPluginsRegistry to the install-dynamic-plugins init container:
+//   - .npmrc replaces the one of dynamic-plugins-npmrc volume (NPM_CONFIG_USERCONFIG)
+//   - CA bundle is trusted by npm (NODE_EXTRA_CA_CERTS) and skopeo (SSL_CERT_DIR) in addition to the system CAs
+//   - OCI credentials are used by skopeo (REGISTRY_AUTH_FILE)
+func addDynamicPluginsRegistry(spec bsv1.BackstageSpec, deployment *appsv1.Deployment, model *BackstageModel) error {
+
+	if spec.Application == nil || spec.Application.DynamicPluginsRegistry == nil {
+		return nil
+	}
+	registry := spec.Application.DynamicPluginsRegistry
+
+	podSpec := &deployment.Spec.Template.Spec
+	i, _ := DynamicPluginsInitContainer(podSpec.InitContainers)
+	if i < 0 {
+		return fmt.Errorf("dynamic plugins registry configured but no InitContainer %s defined", DynamicPluginsInitContainerName)
+	}
+	initContainer := &podSpec.InitContainers[i]
+
+	if registry.Npmrc != nil {
+		secret := &model.ExternalConfig.DynamicPluginsNpmrc
+		key, err := registryObjectKey(*registry.Npmrc, DynamicPluginsNpmrcKey, secretKeys(secret))
+		if err != nil {
+			return err
+		}
+		setVolume(podSpec, corev1.Volume{Name: dynamicPluginsNpmrcVolume, VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: registryObjectName(secret, *registry.Npmrc),
+				Items: []corev1.KeyToPath{{Key: key, Path: ".npmrc"}}},
+		}})
+		if !hasVolumeMount(initContainer, dynamicPluginsNpmrcVolume) {
+			initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
+				Name: dynamicPluginsNpmrcVolume, MountPath: dynamicPluginsNpmrcPath, SubPath: ".npmrc", ReadOnly: true})
+			setEnvVar(initContainer, "NPM_CONFIG_USERCONFIG", dynamicPluginsNpmrcPath)
+		}
+	}
+
+	if registry.CABundle != nil {
+		cm := &model.ExternalConfig.DynamicPluginsCABundle
+		key, err := registryObjectKey(*registry.CABundle, DynamicPluginsCABundleKey, configMapKeys(cm))
+		if err != nil {
+			return err
+		}
+		setVolume(podSpec, corev1.Volume{Name: dynamicPluginsCABundleVolume, VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: registryObjectName(cm, *registry.CABundle)},
+				Items:                []corev1.KeyToPath{{Key: key, Path: DynamicPluginsCABundleKey}}},
+		}})
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
+			Name: dynamicPluginsCABundleVolume, MountPath: dynamicPluginsCABundleDir, ReadOnly: true})
+		setEnvVar(initContainer, "NODE_EXTRA_CA_CERTS", filepath.Join(dynamicPluginsCABundleDir, DynamicPluginsCABundleKey))
+		setEnvVar(initContainer, "SSL_CERT_DIR", dynamicPluginsCABundleDir)
+	}
+
+	if registry.OCIAuth != nil {
+		secret := &model.ExternalConfig.DynamicPluginsOCIAuth
+		key, err := registryObjectKey(*registry.OCIAuth, DynamicPluginsOCIAuthKey, secretKeys(secret))
+		if err != nil {
+			return err
+		}
+		setVolume(podSpec, corev1.Volume{Name: dynamicPluginsOCIAuthVolume, VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: registryObjectName(secret, *registry.OCIAuth),
+				Items: []corev1.KeyToPath{{Key: key, Path: dynamicPluginsOCIAuthFile}}},
+		}})
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
+			Name: dynamicPluginsOCIAuthVolume, MountPath: dynamicPluginsOCIAuthDir, ReadOnly: true})
+		setEnvVar(initContainer, "REGISTRY_AUTH_FILE", filepath.Join(dynamicPluginsOCIAuthDir, dynamicPluginsOCIAuthFile))
+	}
+
+	return nil
+}
+
+// registryObjectName returns the name of the object read by the preprocessor (the mirror for the objects of other namespaces)
+// or the referred name if not read
+func registryObjectName(obj client.Object, ref bsv1.ObjectKeyRef) string {
+	if obj.GetName() != "" {
+		return obj.GetName()
+	}
+	return ref.Name
+}
+
+// registryObjectKey returns the key of the referred object (the default one if not specified),
+// which has to be present in the object if its content is known
+func registryObjectKey(ref bsv1.ObjectKeyRef, defaultKey string, keys map[string]bool) (string, error) {
+	key := ref.Key
+	if key == "" {
+		key = defaultKey
+	}
+	if keys != nil && !keys[key] {
+		return "", fmt.Errorf("dynamic plugins registry object %s has no key %s", ref.Name, key)
+	}
+	return key, nil
+}
+
+func secretKeys(secret *corev1.Secret) map[string]bool {
+	if secret.Data == nil && secret.StringData == nil {
+		return nil
+	}
+	keys := map[string]bool{}
+	for k := range secret.Data {
+		keys[k] = true
+	}
+	for k := range secret.StringData {
+		keys[k] = true
+	}
+	return keys
+}
+
+func configMapKeys(cm *corev1.ConfigMap) map[string]bool {
+	if cm.Data == nil && cm.BinaryData == nil {
+		return nil
+	}
+	keys := map[string]bool{}
+	for k := range cm.Data {
+		keys[k] = true
+	}
+	for k := range cm.BinaryData {
+		keys[k] = true
+	}
+	return keys
+}
+
+// setVolume replaces the pod volume of the same name or adds it
+func setVolume(podSpec *corev1.PodSpec, volume corev1.Volume) {
+	for i, v := range podSpec.Volumes {
+		if v.Name == volume.Name {
+			podSpec.Volumes[i] = volume
+			return
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+}
+
+func hasVolumeMount(container *corev1.Container, volumeName string) bool {
+	for _, vm := range container.VolumeMounts {
+		if vm.Name == volumeName {
+			return true
+		}
+	}
+	return false
+}
+
+// setEnvVar replaces the value of the container environment variable or adds it
+func setEnvVar(container *corev1.Container, name, value string) {
+	for i, e := range container.Env {
+		if e.Name == name {
+			container.Env[i] = corev1.EnvVar{Name: name, Value: value}
+			return
+		}
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+}
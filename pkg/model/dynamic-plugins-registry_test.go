@@ -0,0 +1,81 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"testing"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDynamicPluginsRegistry(t *testing.T) {
+
+	bs := testDynamicPluginsBackstage.DeepCopy()
+	bs.Spec.Application.DynamicPluginsRegistry = &bsv1.DynamicPluginsRegistry{
+		Npmrc:    &bsv1.ObjectKeyRef{Name: "my-npmrc", Key: "npmrc"},
+		CABundle: &bsv1.ObjectKeyRef{Name: "my-ca"},
+		OCIAuth:  &bsv1.ObjectKeyRef{Name: "my-pull-secret"},
+	}
+
+	testObj := createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+	testObj.externalConfig.DynamicPluginsNpmrc = corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-npmrc"},
+		Data: map[string][]byte{"npmrc": []byte("registry=https://npm.example.com")}}
+	testObj.externalConfig.DynamicPluginsCABundle = corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-ca"},
+		Data: map[string]string{DynamicPluginsCABundleKey: "cert"}}
+	testObj.externalConfig.DynamicPluginsOCIAuth = corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-pull-secret"},
+		Data: map[string][]byte{DynamicPluginsOCIAuthKey: []byte("{}")}}
+
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	volumes := map[string]corev1.Volume{}
+	for _, v := range model.backstageDeployment.deployment.Spec.Template.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+	// the default npmrc volume points to the instance Secret
+	assert.Equal(t, "my-npmrc", volumes[dynamicPluginsNpmrcVolume].Secret.SecretName)
+	assert.Equal(t, []corev1.KeyToPath{{Key: "npmrc", Path: ".npmrc"}}, volumes[dynamicPluginsNpmrcVolume].Secret.Items)
+	assert.Equal(t, "my-ca", volumes[dynamicPluginsCABundleVolume].ConfigMap.Name)
+	assert.Equal(t, "my-pull-secret", volumes[dynamicPluginsOCIAuthVolume].Secret.SecretName)
+
+	ic := initContainer(model)
+	mounts := map[string]string{}
+	for _, vm := range ic.VolumeMounts {
+		mounts[vm.Name] = vm.MountPath
+	}
+	assert.Equal(t, dynamicPluginsNpmrcPath, mounts[dynamicPluginsNpmrcVolume])
+	assert.Equal(t, dynamicPluginsCABundleDir, mounts[dynamicPluginsCABundleVolume])
+	assert.Equal(t, dynamicPluginsOCIAuthDir, mounts[dynamicPluginsOCIAuthVolume])
+
+	env := map[string]string{}
+	for _, e := range ic.Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, dynamicPluginsNpmrcPath, env["NPM_CONFIG_USERCONFIG"])
+	assert.Equal(t, "/opt/app-root/src/dynamic-plugins-ca/ca-bundle.crt", env["NODE_EXTRA_CA_CERTS"])
+	assert.Equal(t, dynamicPluginsCABundleDir, env["SSL_CERT_DIR"])
+	assert.Equal(t, "/opt/app-root/src/dynamic-plugins-registry-auth/auth.json", env["REGISTRY_AUTH_FILE"])
+
+	// missing key
+	testObj.externalConfig.DynamicPluginsNpmrc.Data = map[string][]byte{".npmrc": []byte("")}
+	_, err = InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.ErrorContains(t, err, "dynamic plugins registry object my-npmrc has no key npmrc")
+}
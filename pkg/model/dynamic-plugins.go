@@ -25,9 +25,10 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
-const dynamicPluginInitContainerName = "install-dynamic-plugins"
+const DynamicPluginsInitContainerName = "install-dynamic-plugins"
 const DynamicPluginsFile = "dynamic-plugins.yaml"
 
 type DynamicPluginsFactory struct{}
@@ -54,14 +55,19 @@ func addDynamicPlugins(spec v1alpha2.BackstageSpec, deployment *appsv1.Deploymen
 		return nil
 	}
 
+	// merged into the default configuration (see DynamicPlugins.addToModel), which mounts itself
+	if model.dynamicPlugins != nil {
+		return nil
+	}
+
 	if _, ic := DynamicPluginsInitContainer(deployment.Spec.Template.Spec.InitContainers); ic == nil {
-		return fmt.Errorf("validation failed, dynamic plugin name configured but no InitContainer %s defined", dynamicPluginInitContainerName)
+		return fmt.Errorf("validation failed, dynamic plugin name configured but no InitContainer %s defined", DynamicPluginsInitContainerName)
 	}
 
 	dp := DynamicPlugins{ConfigMap: &model.ExternalConfig.DynamicPlugins}
 
-	if dp.ConfigMap.Data == nil || len(dp.ConfigMap.Data) != 1 || dp.ConfigMap.Data[DynamicPluginsFile] == "" {
-		return fmt.Errorf("dynamic plugin configMap expects exactly one key named '%s' ", DynamicPluginsFile)
+	if err := validateDynamicPluginsConfigMap(spec, dp.ConfigMap); err != nil {
+		return err
 	}
 
 	dp.updatePod(deployment)
@@ -88,15 +94,60 @@ func (p *DynamicPlugins) EmptyObject() client.Object {
 }
 
 // implementation of RuntimeObject interface
+// If the dynamic plugins ConfigMap or the inline dynamic plugins are specified, they are merged into the default configuration
+// (see mergeDynamicPlugins). Without the default configuration, the inline plugins are merged into the ConfigMap,
+// and the ConfigMap alone is mounted as is (see addDynamicPlugins)
 func (p *DynamicPlugins) addToModel(model *BackstageModel, backstage v1alpha2.Backstage) (bool, error) {
 
-	if p.ConfigMap == nil || (backstage.Spec.Application != nil && backstage.Spec.Application.DynamicPluginsConfigMapName != "") {
-		return false, nil
+	inline, err := InlineDynamicPlugins(backstage.Spec)
+	if err != nil {
+		return false, err
 	}
+	if p.ConfigMap == nil {
+		if inline == "" {
+			return false, nil
+		}
+		p.ConfigMap = &corev1.ConfigMap{}
+	}
+
+	content := p.ConfigMap.Data[DynamicPluginsFile]
+	if backstage.Spec.Application != nil && backstage.Spec.Application.DynamicPluginsConfigMapName != "" {
+		userConfig := &model.ExternalConfig.DynamicPlugins
+		if err := validateDynamicPluginsConfigMap(backstage.Spec, userConfig); err != nil {
+			return false, err
+		}
+		if content, err = mergeDynamicPlugins(content, userConfig.Data[DynamicPluginsFile]); err != nil {
+			return false, fmt.Errorf("failed to merge dynamic plugins ConfigMap %s with the default one: %w", userConfig.Name, err)
+		}
+	}
+	if inline != "" {
+		if content, err = mergeDynamicPlugins(content, inline); err != nil {
+			return false, fmt.Errorf("failed to merge inline dynamic plugins: %w", err)
+		}
+	}
+	p.ConfigMap.Data = map[string]string{DynamicPluginsFile: content}
+
 	model.setRuntimeObject(p)
+	model.dynamicPlugins = p
 	return true, nil
 }
 
+// InlineDynamicPlugins returns dynamic-plugins.yaml content with the dynamic plugins of the Backstage spec,
+// or empty string if not defined
+func InlineDynamicPlugins(spec v1alpha2.BackstageSpec) (string, error) {
+	if spec.Application == nil || len(spec.Application.DynamicPlugins) == 0 {
+		return "", nil
+	}
+	if err := validateInlineDynamicPlugins(spec); err != nil {
+		return "", err
+	}
+	content, err := yaml.Marshal(map[string]interface{}{"plugins": spec.Application.DynamicPlugins})
+	if err != nil {
+		return "", fmt.Errorf("failed to convert inline dynamic plugins to yaml: %w", err)
+	}
+	return string(content), nil
+}
+
 // implementation of BackstagePodContributor interface
 func (p *DynamicPlugins) updatePod(deployment *appsv1.Deployment) {
 
@@ -128,7 +179,7 @@ func (p *DynamicPlugins) validate(model *BackstageModel, _ v1alpha2.Backstage) e
 
 	_, initContainer := DynamicPluginsInitContainer(model.backstageDeployment.deployment.Spec.Template.Spec.InitContainers)
 	if initContainer == nil {
-		return fmt.Errorf("failed to find initContainer named %s", dynamicPluginInitContainerName)
+		return fmt.Errorf("failed to find initContainer named %s", DynamicPluginsInitContainerName)
 	}
 	// override image with env var
 	// [GA] Do we need this feature?
@@ -149,9 +200,113 @@ func (p *DynamicPlugins) setMetaInfo(backstageName string) {
 // TODO consider to use a label to identify instead
 func DynamicPluginsInitContainer(initContainers []corev1.Container) (int, *corev1.Container) {
 	for i, ic := range initContainers {
-		if ic.Name == dynamicPluginInitContainerName {
+		if ic.Name == DynamicPluginsInitContainerName {
 			return i, &ic
 		}
 	}
 	return -1, nil
 }
+
+// parseDynamicPlugins parses dynamic-plugins.yaml content, which is expected to be an object
+// with optional includes (list of file names) and plugins (list of objects with package)
+func parseDynamicPlugins(content string) (map[string]interface{}, error) {
+	conf := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content), &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DynamicPluginsFile, err)
+	}
+	if conf == nil {
+		conf = map[string]interface{}{}
+	}
+	if includes, ok := conf["includes"]; ok && includes != nil {
+		if _, ok := includes.([]interface{}); !ok {
+			return nil, fmt.Errorf("includes of %s is expected to be a list", DynamicPluginsFile)
+		}
+	}
+	if plugins, ok := conf["plugins"]; ok && plugins != nil {
+		list, ok := plugins.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("plugins of %s is expected to be a list", DynamicPluginsFile)
+		}
+		for i, plugin := range list {
+			entry, ok := plugin.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("plugin %d of %s is expected to be an object", i, DynamicPluginsFile)
+			}
+			if pkg, ok := entry["package"].(string); !ok || pkg == "" {
+				return nil, fmt.Errorf("plugin %d of %s has no package", i, DynamicPluginsFile)
+			}
+		}
+	}
+	return conf, nil
+}
+
+// mergeDynamicPlugins merges user's dynamic-plugins.yaml into the default one.
+// Plugins are matched by package, the fields specified by user (such as disabled or pluginConfig)
+// override the default ones and the plugins not in the default list are appended.
+// Includes are unioned, other fields specified by user override the default ones.
+func mergeDynamicPlugins(defaultContent, userContent string) (string, error) {
+	defaults, err := parseDynamicPlugins(defaultContent)
+	if err != nil {
+		return "", fmt.Errorf("invalid default configuration: %w", err)
+	}
+	user, err := parseDynamicPlugins(userContent)
+	if err != nil {
+		return "", err
+	}
+
+	includes, _ := defaults["includes"].([]interface{})
+	for _, include := range asList(user["includes"]) {
+		if !containsValue(includes, include) {
+			includes = append(includes, include)
+		}
+	}
+
+	plugins, _ := defaults["plugins"].([]interface{})
+	for _, plugin := range asList(user["plugins"]) {
+		userPlugin := plugin.(map[string]interface{})
+		merged := false
+		for _, p := range plugins {
+			defaultPlugin := p.(map[string]interface{})
+			if defaultPlugin["package"] == userPlugin["package"] {
+				for k, v := range userPlugin {
+					defaultPlugin[k] = v
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			plugins = append(plugins, userPlugin)
+		}
+	}
+
+	for k, v := range user {
+		defaults[k] = v
+	}
+	if includes != nil {
+		defaults["includes"] = includes
+	}
+	if plugins != nil {
+		defaults["plugins"] = plugins
+	}
+
+	content, err := yaml.Marshal(defaults)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
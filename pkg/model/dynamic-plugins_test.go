@@ -24,6 +24,7 @@ import (
 	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/stretchr/testify/assert"
@@ -49,7 +50,7 @@ func TestDynamicPluginsValidationFailed(t *testing.T) {
 	testObj := createBackstageTest(*bs).withDefaultConfig(true).
 		addToDefaultConfig("dynamic-plugins.yaml", "raw-dynamic-plugins.yaml")
 
-	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, false, testObj.scheme)
+	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	//"failed object validation, reason: failed to find initContainer named install-dynamic-plugins")
 	assert.Error(t, err)
@@ -70,7 +71,7 @@ func TestDynamicPluginsInvalidKeyName(t *testing.T) {
 		Data:       map[string]string{"WrongKeyName.yml": "tt"},
 	}
 
-	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, false, testObj.scheme)
+	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "expects exactly one key named 'dynamic-plugins.yaml'")
@@ -86,7 +87,7 @@ func TestDefaultDynamicPlugins(t *testing.T) {
 		addToDefaultConfig("dynamic-plugins.yaml", "raw-dynamic-plugins.yaml").
 		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
 
-	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, model.backstageDeployment)
@@ -115,10 +116,10 @@ func TestDefaultAndSpecifiedDynamicPlugins(t *testing.T) {
 
 	testObj.externalConfig.DynamicPlugins = corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{Name: "dplugin"},
-		Data:       map[string]string{DynamicPluginsFile: "tt"},
+		Data:       map[string]string{DynamicPluginsFile: "plugins: []"},
 	}
 
-	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, model)
@@ -127,9 +128,128 @@ func TestDefaultAndSpecifiedDynamicPlugins(t *testing.T) {
 	assert.NotNil(t, ic)
 	//dynamic-plugins-root
 	//dynamic-plugins-npmrc
-	//vol-dplugin
+	//vol-backstage-dynamic-plugins-bs (merged)
 	assert.Equal(t, 3, len(ic.VolumeMounts))
-	assert.Equal(t, utils.GenerateVolumeNameFromCmOrSecret("dplugin"), ic.VolumeMounts[2].Name)
+	assert.Equal(t, utils.GenerateVolumeNameFromCmOrSecret(DynamicPluginsDefaultName(bs.Name)), ic.VolumeMounts[2].Name)
+}
+
+func TestMergeDynamicPlugins(t *testing.T) {
+
+	bs := testDynamicPluginsBackstage.DeepCopy()
+	bs.Spec.Application.DynamicPluginsConfigMapName = "dplugin"
+
+	testObj := createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("dynamic-plugins.yaml", "merge-dynamic-plugins.yaml").
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+
+	testObj.externalConfig.DynamicPlugins = corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "dplugin"},
+		Data: map[string]string{DynamicPluginsFile: `
+includes:
+  - dynamic-plugins.default.yaml
+  - my-plugins.yaml
+plugins:
+  - package: ./dynamic-plugins/dist/plugin-b
+    disabled: false
+    pluginConfig:
+      b: user
+  - package: ./dynamic-plugins/dist/plugin-c
+`},
+	}
+
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	assert.NotNil(t, model.dynamicPlugins)
+	assert.Equal(t, DynamicPluginsDefaultName(bs.Name), model.dynamicPlugins.ConfigMap.Name)
+
+	assert.YAMLEq(t, `
+includes:
+  - dynamic-plugins.default.yaml
+  - my-plugins.yaml
+plugins:
+  - package: ./dynamic-plugins/dist/plugin-a
+    disabled: true
+  - package: ./dynamic-plugins/dist/plugin-b
+    disabled: false
+    integrity: sha512-abc
+    pluginConfig:
+      b: user
+  - package: ./dynamic-plugins/dist/plugin-c
+`, model.dynamicPlugins.ConfigMap.Data[DynamicPluginsFile])
+
+	// invalid user configuration
+	testObj.externalConfig.DynamicPlugins.Data[DynamicPluginsFile] = "plugins:\n  - disabled: true"
+	_, err = InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.ErrorContains(t, err, "has no package")
+}
+
+func TestInlineDynamicPlugins(t *testing.T) {
+
+	bs := testDynamicPluginsBackstage.DeepCopy()
+	bs.Spec.Application.DynamicPluginsConfigMapName = "dplugin"
+	bs.Spec.Application.DynamicPlugins = []bsv1.DynamicPlugin{
+		{Package: "./dynamic-plugins/dist/plugin-a", Disabled: ptr.To(false),
+			PluginConfig: &apiextensionsv1.JSON{Raw: []byte(`{"a":{"enabled":true}}`)}},
+		{Package: "@org/plugin-d", Integrity: "sha512-def"},
+	}
+
+	testObj := createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("dynamic-plugins.yaml", "merge-dynamic-plugins.yaml").
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+
+	testObj.externalConfig.DynamicPlugins = corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "dplugin"},
+		Data: map[string]string{DynamicPluginsFile: `
+plugins:
+  - package: ./dynamic-plugins/dist/plugin-a
+    pluginConfig:
+      a:
+        enabled: false
+`},
+	}
+
+	model, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+
+	// inline plugins override the ConfigMap ones, which override the default ones
+	assert.YAMLEq(t, `
+includes:
+  - dynamic-plugins.default.yaml
+plugins:
+  - package: ./dynamic-plugins/dist/plugin-a
+    disabled: false
+    pluginConfig:
+      a:
+        enabled: true
+  - package: ./dynamic-plugins/dist/plugin-b
+    disabled: true
+    integrity: sha512-abc
+    pluginConfig:
+      b: default
+  - package: "@org/plugin-d"
+    integrity: sha512-def
+`, model.dynamicPlugins.ConfigMap.Data[DynamicPluginsFile])
+
+	// no default dynamic plugins configuration, the inline plugins only
+	bs.Spec.Application.DynamicPluginsConfigMapName = ""
+	testObj = createBackstageTest(*bs).withDefaultConfig(true).
+		addToDefaultConfig("deployment.yaml", "janus-deployment.yaml")
+	model, err = InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Equal(t, DynamicPluginsDefaultName(bs.Name), model.dynamicPlugins.ConfigMap.Name)
+	assert.YAMLEq(t, `
+plugins:
+  - package: ./dynamic-plugins/dist/plugin-a
+    disabled: false
+    pluginConfig:
+      a:
+        enabled: true
+  - package: "@org/plugin-d"
+    integrity: sha512-def
+`, model.dynamicPlugins.ConfigMap.Data[DynamicPluginsFile])
+	ic := initContainer(model)
+	assert.Equal(t, utils.GenerateVolumeNameFromCmOrSecret(DynamicPluginsDefaultName(bs.Name)), ic.VolumeMounts[len(ic.VolumeMounts)-1].Name)
 }
 
 func TestDynamicPluginsFailOnArbitraryDepl(t *testing.T) {
@@ -140,14 +260,14 @@ func TestDynamicPluginsFailOnArbitraryDepl(t *testing.T) {
 	testObj := createBackstageTest(*bs).withDefaultConfig(true).
 		addToDefaultConfig("dynamic-plugins.yaml", "raw-dynamic-plugins.yaml")
 
-	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, false, testObj.scheme)
+	_, err := InitObjects(context.TODO(), *bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.Error(t, err)
 }
 
 func initContainer(model *BackstageModel) *corev1.Container {
 	for _, v := range model.backstageDeployment.deployment.Spec.Template.Spec.InitContainers {
-		if v.Name == dynamicPluginInitContainerName {
+		if v.Name == DynamicPluginsInitContainerName {
 			return &v
 		}
 	}
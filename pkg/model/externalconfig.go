@@ -18,14 +18,34 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"reflect"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ExtConfigSyncLabel and BackstageNameAnnotation were set on the referred ConfigMaps and Secrets by the former versions
+// of the Operator to watch them, they are removed once the referring Backstage runtime is applied
 const ExtConfigSyncLabel = "rhdh.redhat.com/ext-config-sync"
 const BackstageNameAnnotation = "rhdh.redhat.com/backstage-name"
 
+// HasLegacyMetadata returns true if the object has the label or annotation set by the former versions of the Operator
+func HasLegacyMetadata(obj client.Object) bool {
+	_, labeled := obj.GetLabels()[ExtConfigSyncLabel]
+	_, annotated := obj.GetAnnotations()[BackstageNameAnnotation]
+	return labeled || annotated
+}
+
+// MirrorLabel marks the copies of ConfigMaps and Secrets of other namespaces (see Mirror)
+const MirrorLabel = "rhdh.redhat.com/mirror"
+
+// MirroredFromAnnotation refers the original object (<namespace>/<name>) of the mirror
+const MirroredFromAnnotation = "rhdh.redhat.com/mirrored-from"
+
 type ExternalConfig struct {
 	RawConfig           map[string]string
 	AppConfigs          map[string]corev1.ConfigMap
@@ -34,10 +54,64 @@ type ExternalConfig struct {
 	ExtraEnvConfigMaps  map[string]corev1.ConfigMap
 	ExtraEnvSecrets     map[string]corev1.Secret
 	DynamicPlugins      corev1.ConfigMap
+	// objects of spec.application.dynamicPluginsRegistry
+	DynamicPluginsNpmrc    corev1.Secret
+	DynamicPluginsCABundle corev1.ConfigMap
+	DynamicPluginsOCIAuth  corev1.Secret
+	// copies of the objects referred from other namespaces, to be applied into the Backstage namespace
+	Mirrors []client.Object
+	// the referred objects still stamped by the former versions of the Operator (see HasLegacyMetadata),
+	// to be cleaned once the Backstage runtime is applied
+	LegacyStamped []client.Object
+	// entries of the dynamic plugins cache used by the live ReplicaSets of the Backstage Deployment (see DynamicPluginsCacheKeys)
+	DynamicPluginsCacheKeys []string
 
 	syncedContent []byte
 }
 
+// AddMirror adds the mirror of the object of another namespace, unless it is already added
+// (the object is referred more than once)
+func (e *ExternalConfig) AddMirror(mirror client.Object) {
+	for _, m := range e.Mirrors {
+		if m.GetName() == mirror.GetName() && reflect.TypeOf(m) == reflect.TypeOf(mirror) {
+			return
+		}
+	}
+	e.Mirrors = append(e.Mirrors, mirror)
+}
+
+// ExternalConfigKey returns the key of the object referred by ref in the ExternalConfig maps
+func ExternalConfigKey(ref bsv1.ObjectKeyRef) string {
+	if ref.Namespace != "" {
+		return ref.Namespace + "/" + ref.Name
+	}
+	return ref.Name
+}
+
+// MirrorName returns the name of the copy of the object of another namespace in the Backstage namespace
+func MirrorName(backstageName, namespace, name string) string {
+	return utils.GenerateRuntimeObjectName(backstageName, fmt.Sprintf("mirror-%s-%s", namespace, name))
+}
+
+// Mirror returns the copy (data only) of the ConfigMap or Secret of another namespace to be created
+// in the namespace of the Backstage
+func Mirror(obj client.Object, backstage bsv1.Backstage) (client.Object, error) {
+	meta := metav1.ObjectMeta{
+		Name:        MirrorName(backstage.Name, obj.GetNamespace(), obj.GetName()),
+		Namespace:   backstage.Namespace,
+		Labels:      map[string]string{MirrorLabel: "true"},
+		Annotations: map[string]string{MirroredFromAnnotation: obj.GetNamespace() + "/" + obj.GetName()},
+	}
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		return &corev1.ConfigMap{ObjectMeta: meta, Data: o.Data, BinaryData: o.BinaryData}, nil
+	case *corev1.Secret:
+		return &corev1.Secret{ObjectMeta: meta, Type: o.Type, Data: o.Data, StringData: o.StringData}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T, only ConfigMaps and Secrets can be mirrored", obj)
+	}
+}
+
 func NewExternalConfig() ExternalConfig {
 
 	return ExternalConfig{
@@ -59,9 +133,41 @@ func (e *ExternalConfig) GetHash() string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func (e *ExternalConfig) AddToSyncedConfig(content client.Object) error {
+// AddToSyncedConfig adds the content of ConfigMap or Secret to the external config the hash is calculated for.
+// Only the data (and binaryData) is taken into account, metadata changes (labels, annotations, resourceVersion etc.)
+// do not change the hash. If key is not empty, only the value of this key is taken.
+func (e *ExternalConfig) AddToSyncedConfig(content client.Object, key string) error {
+
+	hashed := hashedContent{Name: content.GetName()}
+	switch obj := content.(type) {
+	case *corev1.ConfigMap:
+		hashed.Kind = "ConfigMap"
+		hashed.Data = obj.Data
+		hashed.BinaryData = obj.BinaryData
+	case *corev1.Secret:
+		hashed.Kind = "Secret"
+		hashed.BinaryData = obj.Data
+		// not returned by API server, but can be set on the object to be created
+		if len(obj.StringData) > 0 {
+			hashed.BinaryData = map[string][]byte{}
+			for k, v := range obj.Data {
+				hashed.BinaryData[k] = v
+			}
+			for k, v := range obj.StringData {
+				hashed.BinaryData[k] = []byte(v)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported external config object type %T", content)
+	}
+
+	if key != "" {
+		hashed.Data = onlyKey(hashed.Data, key)
+		hashed.BinaryData = onlyKey(hashed.BinaryData, key)
+	}
 
-	d, err := json.Marshal(content)
+	// map keys are sorted by json.Marshal, so the representation is canonical
+	d, err := json.Marshal(hashed)
 	if err != nil {
 		return err
 	}
@@ -69,3 +175,18 @@ func (e *ExternalConfig) AddToSyncedConfig(content client.Object) error {
 	e.syncedContent = append(e.syncedContent, d...)
 	return nil
 }
+
+// hashedContent is the part of external config object the hash is calculated for
+type hashedContent struct {
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name"`
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+func onlyKey[V any](data map[string]V, key string) map[string]V {
+	if value, ok := data[key]; ok {
+		return map[string]V{key: value}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hashOf(t *testing.T, key string, objects ...*corev1.ConfigMap) string {
+	ec := NewExternalConfig()
+	for _, obj := range objects {
+		assert.NoError(t, ec.AddToSyncedConfig(obj, key))
+	}
+	return ec.GetHash()
+}
+
+func TestHashMetadataIgnored(t *testing.T) {
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm1", ResourceVersion: "1"},
+		Data:       map[string]string{"a.yaml": "a", "b.yaml": "b"},
+	}
+	hash := hashOf(t, "", cm)
+
+	changed := cm.DeepCopy()
+	changed.ResourceVersion = "2"
+	changed.Labels = map[string]string{"rotated": "true"}
+	changed.Annotations = map[string]string{"note": "touched"}
+	changed.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	assert.Equal(t, hash, hashOf(t, "", changed))
+
+	changed.Data["b.yaml"] = "b2"
+	assert.NotEqual(t, hash, hashOf(t, "", changed))
+
+	changed = cm.DeepCopy()
+	changed.BinaryData = map[string][]byte{"c.bin": {1}}
+	assert.NotEqual(t, hash, hashOf(t, "", changed))
+}
+
+func TestHashOnlyKey(t *testing.T) {
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm1"},
+		Data:       map[string]string{"a.yaml": "a", "b.yaml": "b"},
+	}
+	hash := hashOf(t, "a.yaml", cm)
+
+	changed := cm.DeepCopy()
+	changed.Data["b.yaml"] = "b2"
+	assert.Equal(t, hash, hashOf(t, "a.yaml", changed))
+
+	changed.Data["a.yaml"] = "a2"
+	assert.NotEqual(t, hash, hashOf(t, "a.yaml", changed))
+}
+
+func TestHashSecret(t *testing.T) {
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret1"},
+		Data:       map[string][]byte{"TOKEN": []byte("t1")},
+	}
+	ec := NewExternalConfig()
+	assert.NoError(t, ec.AddToSyncedConfig(secret, ""))
+	hash := ec.GetHash()
+
+	secret.Labels = map[string]string{"rotated": "true"}
+	ec = NewExternalConfig()
+	assert.NoError(t, ec.AddToSyncedConfig(secret, ""))
+	assert.Equal(t, hash, ec.GetHash())
+
+	secret.Data["TOKEN"] = []byte("t2")
+	ec = NewExternalConfig()
+	assert.NoError(t, ec.AddToSyncedConfig(secret, ""))
+	assert.NotEqual(t, hash, ec.GetHash())
+
+	assert.Error(t, ec.AddToSyncedConfig(&corev1.Service{}, ""))
+}
+
+func TestMirror(t *testing.T) {
+
+	backstage := bsv1.Backstage{ObjectMeta: metav1.ObjectMeta{Name: "bs1", Namespace: "ns1"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "shared", Labels: map[string]string{"team": "platform"}},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"TOKEN": []byte("t1")},
+	}
+
+	mirror, err := Mirror(secret, backstage)
+	assert.NoError(t, err)
+	assert.Equal(t, MirrorName("bs1", "shared", "auth"), mirror.GetName())
+	assert.Equal(t, "ns1", mirror.GetNamespace())
+	// data only, not the metadata of the original
+	assert.Equal(t, map[string]string{MirrorLabel: "true"}, mirror.GetLabels())
+	assert.Equal(t, "shared/auth", mirror.GetAnnotations()[MirroredFromAnnotation])
+	assert.Equal(t, secret.Data, mirror.(*corev1.Secret).Data)
+	assert.Equal(t, corev1.SecretTypeOpaque, mirror.(*corev1.Secret).Type)
+
+	ec := NewExternalConfig()
+	ec.AddMirror(mirror)
+	ec.AddMirror(mirror.DeepCopyObject().(*corev1.Secret))
+	assert.Len(t, ec.Mirrors, 1)
+
+	assert.Equal(t, "shared/auth", ExternalConfigKey(bsv1.ObjectKeyRef{Name: "auth", Namespace: "shared"}))
+	assert.Equal(t, "auth", ExternalConfigKey(bsv1.ObjectKeyRef{Name: "auth"}))
+
+	_, err = Mirror(&corev1.Service{}, backstage)
+	assert.Error(t, err)
+}
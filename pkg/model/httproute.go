@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HTTPRouteGVK is Gateway API HTTPRoute kind.
+// HTTPRoute is handled as unstructured object, so the Operator does not depend on Gateway API types
+var HTTPRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+type BackstageHTTPRouteFactory struct{}
+
+func (f BackstageHTTPRouteFactory) newBackstageObject() RuntimeObject {
+	return &BackstageHTTPRoute{}
+}
+
+type BackstageHTTPRoute struct {
+	httpRoute *unstructured.Unstructured
+}
+
+func HTTPRouteName(backstageName string) string {
+	return utils.GenerateRuntimeObjectName(backstageName, "backstage")
+}
+
+func init() {
+	registerConfig("httproute.yaml", BackstageHTTPRouteFactory{})
+}
+
+func (b *BackstageHTTPRoute) setHTTPRoute(specified *bsv1.HTTPRoute) error {
+
+	if specified.ParentRef != nil {
+		parentRef := map[string]interface{}{
+			"name": specified.ParentRef.Name,
+		}
+		if len(specified.ParentRef.Namespace) > 0 {
+			parentRef["namespace"] = specified.ParentRef.Namespace
+		}
+		if len(specified.ParentRef.SectionName) > 0 {
+			parentRef["sectionName"] = specified.ParentRef.SectionName
+		}
+		if err := unstructured.SetNestedSlice(b.httpRoute.Object, []interface{}{parentRef}, "spec", "parentRefs"); err != nil {
+			return err
+		}
+	}
+	if len(specified.Hostnames) > 0 {
+		if err := unstructured.SetNestedStringSlice(b.httpRoute.Object, specified.Hostnames, "spec", "hostnames"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageHTTPRoute) Object() client.Object {
+	return b.httpRoute
+}
+
+func (b *BackstageHTTPRoute) setObject(obj client.Object) {
+	b.httpRoute = nil
+	if obj != nil {
+		b.httpRoute = obj.(*unstructured.Unstructured)
+	}
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageHTTPRoute) EmptyObject() client.Object {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(HTTPRouteGVK)
+	return u
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageHTTPRoute) addToModel(model *BackstageModel, backstage bsv1.Backstage) (bool, error) {
+
+	// Gateway API is not available
+	if !model.platform.HasGatewayAPI {
+		return false, nil
+	}
+
+	// HTTPRoute explicitly disabled
+	if !backstage.Spec.IsHTTPRouteEnabled() {
+		return false, nil
+	}
+
+	specDefined := backstage.Spec.Application != nil && backstage.Spec.Application.HTTPRoute != nil
+
+	// no default HTTPRoute and not defined
+	if b.httpRoute == nil && !specDefined {
+		return false, nil
+	}
+
+	// no default HTTPRoute but defined in the spec -> create default
+	if b.httpRoute == nil {
+		b.httpRoute = b.EmptyObject().(*unstructured.Unstructured)
+	}
+
+	// merge with specified (pieces) if any
+	if specDefined {
+		if err := b.setHTTPRoute(backstage.Spec.Application.HTTPRoute); err != nil {
+			return false, fmt.Errorf("failed to set HTTPRoute: %w", err)
+		}
+	}
+
+	model.httpRoute = b
+	model.setRuntimeObject(b)
+
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+// makes sure the HTTPRoute is attached to a Gateway and routes to the Backstage Service
+func (b *BackstageHTTPRoute) validate(model *BackstageModel, _ bsv1.Backstage) error {
+
+	parentRefs, _, _ := unstructured.NestedSlice(b.httpRoute.Object, "spec", "parentRefs")
+	if len(parentRefs) == 0 {
+		return fmt.Errorf("HTTPRoute has no parent Gateway, make sure spec.application.httpRoute.parentRef is set")
+	}
+
+	service := model.backstageService.service
+	backendRef := map[string]interface{}{
+		"name": service.Name,
+	}
+	if len(service.Spec.Ports) > 0 {
+		backendRef["port"] = int64(service.Spec.Ports[0].Port)
+	}
+	backendRefs := []interface{}{backendRef}
+
+	rules, _, _ := unstructured.NestedSlice(b.httpRoute.Object, "spec", "rules")
+	if len(rules) == 0 {
+		rules = []interface{}{map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{
+				"path": map[string]interface{}{"type": "PathPrefix", "value": "/"},
+			}},
+		}}
+	}
+	for _, rule := range rules {
+		if r, ok := rule.(map[string]interface{}); ok {
+			r["backendRefs"] = backendRefs
+		}
+	}
+	return unstructured.SetNestedSlice(b.httpRoute.Object, rules, "spec", "rules")
+}
+
+func (b *BackstageHTTPRoute) setMetaInfo(backstageName string) {
+	b.httpRoute.SetName(HTTPRouteName(backstageName))
+}
@@ -0,0 +1,120 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"k8s.io/utils/ptr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecifiedHTTPRoute(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestSpecifiedHTTPRoute",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				HTTPRoute: &bsv1.HTTPRoute{
+					ParentRef: &bsv1.GatewayRef{Name: "my-gateway", Namespace: "gateways", SectionName: "https"},
+					Hostnames: []string{"backstage.example.com"},
+				},
+			},
+		},
+	}
+	assert.True(t, bs.Spec.IsHTTPRouteEnabled())
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+
+	// no Gateway API
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.httpRoute)
+
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{HasGatewayAPI: true}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.NotNil(t, model.httpRoute)
+
+	httpRoute := model.httpRoute.httpRoute
+	assert.Equal(t, HTTPRouteGVK, httpRoute.GroupVersionKind())
+	assert.Equal(t, HTTPRouteName(bs.Name), httpRoute.GetName())
+	assert.Equal(t, "ns123", httpRoute.GetNamespace())
+
+	parentRefs, _, _ := unstructured.NestedSlice(httpRoute.Object, "spec", "parentRefs")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "my-gateway", "namespace": "gateways", "sectionName": "https"}}, parentRefs)
+	hostnames, _, _ := unstructured.NestedStringSlice(httpRoute.Object, "spec", "hostnames")
+	assert.Equal(t, []string{"backstage.example.com"}, hostnames)
+
+	rules, _, _ := unstructured.NestedSlice(httpRoute.Object, "spec", "rules")
+	assert.Equal(t, 1, len(rules))
+	backendRefs, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "backendRefs")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name": model.backstageService.service.Name,
+		"port": int64(model.backstageService.service.Spec.Ports[0].Port),
+	}}, backendRefs)
+}
+
+func TestHTTPRouteWithoutParent(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestHTTPRouteWithoutParent",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				HTTPRoute: &bsv1.HTTPRoute{},
+			},
+		},
+	}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+
+	_, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{HasGatewayAPI: true}, testObj.scheme)
+	assert.ErrorContains(t, err, "no parent Gateway")
+}
+
+func TestDisabledHTTPRoute(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestDisabledHTTPRoute",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				HTTPRoute: &bsv1.HTTPRoute{
+					Enabled:   ptr.To(false),
+					ParentRef: &bsv1.GatewayRef{Name: "my-gateway"},
+				},
+			},
+		},
+	}
+	assert.False(t, bs.Spec.IsHTTPRouteEnabled())
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{HasGatewayAPI: true}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.httpRoute)
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+	"redhat-developer/red-hat-developer-hub-operator/pkg/utils"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type BackstageIngressFactory struct{}
+
+func (f BackstageIngressFactory) newBackstageObject() RuntimeObject {
+	return &BackstageIngress{}
+}
+
+type BackstageIngress struct {
+	ingress *networkingv1.Ingress
+}
+
+func IngressName(backstageName string) string {
+	return utils.GenerateRuntimeObjectName(backstageName, "backstage")
+}
+
+func init() {
+	registerConfig("ingress.yaml", BackstageIngressFactory{})
+}
+
+func (b *BackstageIngress) setIngress(specified *bsv1.Ingress) {
+
+	if len(specified.Host) > 0 {
+		for i := range b.ingress.Spec.Rules {
+			b.ingress.Spec.Rules[i].Host = specified.Host
+		}
+	}
+	if specified.ClassName != nil {
+		b.ingress.Spec.IngressClassName = specified.ClassName
+	}
+	if len(specified.Annotations) > 0 {
+		annotations := b.ingress.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for name, value := range specified.Annotations {
+			annotations[name] = value
+		}
+		b.ingress.SetAnnotations(annotations)
+	}
+	if len(specified.TLSSecretName) > 0 {
+		var hosts []string
+		if len(specified.Host) > 0 {
+			hosts = []string{specified.Host}
+		}
+		b.ingress.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      hosts,
+			SecretName: specified.TLSSecretName,
+		}}
+	}
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageIngress) Object() client.Object {
+	return b.ingress
+}
+
+func (b *BackstageIngress) setObject(obj client.Object) {
+	b.ingress = nil
+	if obj != nil {
+		b.ingress = obj.(*networkingv1.Ingress)
+	}
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageIngress) EmptyObject() client.Object {
+	return &networkingv1.Ingress{}
+}
+
+// implementation of RuntimeObject interface
+func (b *BackstageIngress) addToModel(model *BackstageModel, backstage bsv1.Backstage) (bool, error) {
+
+	// Openshift uses Route
+	if model.platform.IsOpenShift {
+		return false, nil
+	}
+
+	// ingress explicitly disabled
+	if !backstage.Spec.IsIngressEnabled() {
+		return false, nil
+	}
+
+	specDefined := backstage.Spec.Application != nil && backstage.Spec.Application.Ingress != nil
+
+	// no default ingress and not defined
+	if b.ingress == nil && !specDefined {
+		return false, nil
+	}
+
+	// no default ingress but defined in the spec -> create default
+	if b.ingress == nil {
+		b.ingress = &networkingv1.Ingress{}
+	}
+
+	// make sure there is a rule to route to Backstage service (set in validate)
+	if len(b.ingress.Spec.Rules) == 0 {
+		b.ingress.Spec.Rules = []networkingv1.IngressRule{{
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: ptr.To(networkingv1.PathTypePrefix),
+					}},
+				},
+			},
+		}}
+	}
+
+	// merge with specified (pieces) if any
+	if specDefined {
+		b.setIngress(backstage.Spec.Application.Ingress)
+	}
+
+	model.ingress = b
+	model.setRuntimeObject(b)
+
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+// points all the rules' paths to the Backstage Service
+func (b *BackstageIngress) validate(model *BackstageModel, _ bsv1.Backstage) error {
+	service := model.backstageService.service
+	port := networkingv1.ServiceBackendPort{}
+	if len(service.Spec.Ports) > 0 {
+		if service.Spec.Ports[0].Name != "" {
+			port.Name = service.Spec.Ports[0].Name
+		} else {
+			port.Number = service.Spec.Ports[0].Port
+		}
+	}
+	for i := range b.ingress.Spec.Rules {
+		if b.ingress.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+		for j := range b.ingress.Spec.Rules[i].HTTP.Paths {
+			b.ingress.Spec.Rules[i].HTTP.Paths[j].Backend = networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: service.Name,
+					Port: port,
+				},
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BackstageIngress) setMetaInfo(backstageName string) {
+	b.ingress.SetName(IngressName(backstageName))
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"k8s.io/utils/ptr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultIngress(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestDefaultIngress",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				Ingress: &bsv1.Ingress{
+					Host: "backstage.example.com",
+				},
+			},
+		},
+	}
+	assert.True(t, bs.Spec.IsIngressEnabled())
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.NotNil(t, model.ingress)
+
+	ingress := model.ingress.ingress
+	assert.Equal(t, IngressName(bs.Name), ingress.Name)
+	assert.Nil(t, ingress.Spec.IngressClassName)
+	assert.Empty(t, ingress.Spec.TLS)
+	assert.Equal(t, 1, len(ingress.Spec.Rules))
+	assert.Equal(t, "backstage.example.com", ingress.Spec.Rules[0].Host)
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, "/", path.Path)
+	assert.Equal(t, model.backstageService.service.Name, path.Backend.Service.Name)
+	assert.Equal(t, model.backstageService.service.Spec.Ports[0].Name, path.Backend.Service.Port.Name)
+}
+
+func TestSpecifiedIngress(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestSpecifiedIngress",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				Ingress: &bsv1.Ingress{
+					Enabled:       ptr.To(true),
+					Host:          "backstage.example.com",
+					ClassName:     ptr.To("nginx"),
+					TLSSecretName: "my-tls",
+					Annotations:   map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+				},
+			},
+		},
+	}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("ingress.yaml", "raw-ingress.yaml")
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.NotNil(t, model.ingress)
+
+	ingress := model.ingress.ingress
+	assert.Equal(t, IngressName(bs.Name), ingress.Name)
+	// from spec
+	assert.Equal(t, "nginx", *ingress.Spec.IngressClassName)
+	assert.Equal(t, "backstage.example.com", ingress.Spec.Rules[0].Host)
+	assert.Equal(t, []networkingv1.IngressTLS{{Hosts: []string{"backstage.example.com"}, SecretName: "my-tls"}}, ingress.Spec.TLS)
+	assert.Equal(t, "letsencrypt", ingress.Annotations["cert-manager.io/cluster-issuer"])
+	// from raw config
+	assert.Equal(t, "10m", ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"])
+	assert.Equal(t, "/default", ingress.Spec.Rules[0].HTTP.Paths[0].Path)
+	assert.Equal(t, model.backstageService.service.Name, ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+}
+
+func TestDisabledIngress(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestDisabledIngress",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				Ingress: &bsv1.Ingress{
+					Enabled: ptr.To(false),
+					Host:    "backstage.example.com",
+				},
+			},
+		},
+	}
+	assert.False(t, bs.Spec.IsIngressEnabled())
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("ingress.yaml", "raw-ingress.yaml")
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.ingress)
+}
+
+func TestNoIngressOnOpenshift(t *testing.T) {
+	bs := bsv1.Backstage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "TestNoIngressOnOpenshift",
+			Namespace: "ns123",
+		},
+		Spec: bsv1.BackstageSpec{
+			Application: &bsv1.Application{
+				Ingress: &bsv1.Ingress{},
+			},
+		},
+	}
+
+	testObj := createBackstageTest(bs).withDefaultConfig(true)
+
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.ingress)
+
+	// no spec and no default -> no Ingress on non-Openshift as well
+	bs.Spec.Application.Ingress = nil
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
+	assert.NoError(t, err)
+	assert.Nil(t, model.ingress)
+}
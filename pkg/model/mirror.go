@@ -0,0 +1,60 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MirroredObject is a copy of the ConfigMap or Secret referred from another namespace (see ExternalConfig.Mirrors).
+// It is not configured with default or raw config, but added to the model by InitObjects for each mirror
+type MirroredObject struct {
+	obj client.Object
+}
+
+// implementation of RuntimeObject interface
+func (m *MirroredObject) Object() client.Object {
+	return m.obj
+}
+
+// implementation of RuntimeObject interface
+func (m *MirroredObject) setObject(obj client.Object) {
+	m.obj = obj
+}
+
+// implementation of RuntimeObject interface
+func (m *MirroredObject) EmptyObject() client.Object {
+	return reflect.New(reflect.TypeOf(m.obj).Elem()).Interface().(client.Object)
+}
+
+// implementation of RuntimeObject interface
+func (m *MirroredObject) addToModel(model *BackstageModel, _ bsv1.Backstage) (bool, error) {
+	model.RuntimeObjects = append(model.RuntimeObjects, m)
+	return true, nil
+}
+
+// implementation of RuntimeObject interface
+func (m *MirroredObject) validate(_ *BackstageModel, _ bsv1.Backstage) error {
+	return nil
+}
+
+// implementation of RuntimeObject interface
+// the name is set by Mirror already
+func (m *MirroredObject) setMetaInfo(_ string) {
+}
@@ -104,7 +104,7 @@ func (b *BackstageRoute) EmptyObject() client.Object {
 func (b *BackstageRoute) addToModel(model *BackstageModel, backstage bsv1.Backstage) (bool, error) {
 
 	// not Openshift
-	if !model.isOpenshift {
+	if !model.platform.IsOpenShift {
 		return false, nil
 	}
 
@@ -127,6 +127,9 @@ func (b *BackstageRoute) addToModel(model *BackstageModel, backstage bsv1.Backst
 
 	// merge with specified (pieces) if any
 	if specDefined {
+		if err := validateRouteTLS(backstage.Spec); err != nil {
+			return false, err
+		}
 		b.setRoute(backstage.Spec.Application.Route)
 	}
 
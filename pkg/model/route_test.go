@@ -45,7 +45,7 @@ func TestDefaultRoute(t *testing.T) {
 
 	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("route.yaml", "raw-route.yaml")
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 
 	assert.NoError(t, err)
 
@@ -83,7 +83,7 @@ func TestSpecifiedRoute(t *testing.T) {
 
 	// Test w/o default route configured
 	testObjNoDef := createBackstageTest(bs).withDefaultConfig(true)
-	model, err := InitObjects(context.TODO(), bs, testObjNoDef.externalConfig, true, true, testObjNoDef.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObjNoDef.externalConfig, true, Platform{IsOpenShift: true}, testObjNoDef.scheme)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, model.route)
@@ -94,7 +94,7 @@ func TestSpecifiedRoute(t *testing.T) {
 
 	// Test with default route configured
 	testObjWithDef := testObjNoDef.addToDefaultConfig("route.yaml", "raw-route.yaml")
-	model, err = InitObjects(context.TODO(), bs, testObjWithDef.externalConfig, true, true, testObjWithDef.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObjWithDef.externalConfig, true, Platform{IsOpenShift: true}, testObjWithDef.scheme)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, model.route)
@@ -127,13 +127,13 @@ func TestDisabledRoute(t *testing.T) {
 
 	// With def route config
 	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("route.yaml", "raw-route.yaml")
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.Nil(t, model.route)
 
 	// W/o def route config
 	testObj = createBackstageTest(bs).withDefaultConfig(true)
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.Nil(t, model.route)
 
@@ -152,13 +152,13 @@ func TestExcludedRoute(t *testing.T) {
 
 	// With def route config - create default route
 	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("route.yaml", "raw-route.yaml")
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.NotNil(t, model.route)
 
 	// W/o def route config - do not create route
 	testObj = createBackstageTest(bs).withDefaultConfig(true)
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.Nil(t, model.route)
 }
@@ -179,13 +179,13 @@ func TestEnabledRoute(t *testing.T) {
 
 	// With def route config
 	testObj := createBackstageTest(bs).withDefaultConfig(true).addToDefaultConfig("route.yaml", "raw-route.yaml")
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.NotNil(t, model.route)
 
 	// W/o def route config
 	testObj = createBackstageTest(bs).withDefaultConfig(true)
-	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, true, testObj.scheme)
+	model, err = InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{IsOpenShift: true}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.NotNil(t, model.route)
 
@@ -40,10 +40,18 @@ const BackstageAppLabel = "rhdh.redhat.com/app"
 // There are all possible objects for configuration
 var runtimeConfig []ObjectConfig
 
+// Platform is the set of capabilities of the cluster the objects are made for
+type Platform struct {
+	// the cluster is OpenShift, so the Route is made instead of the Ingress
+	IsOpenShift bool
+	// Gateway API (gateway.networking.k8s.io) is available, so the HTTPRoute can be made
+	HasGatewayAPI bool
+}
+
 // BackstageModel represents internal object model
 type BackstageModel struct {
 	localDbEnabled bool
-	isOpenshift    bool
+	platform       Platform
 
 	backstageDeployment *BackstageDeployment
 	backstageService    *BackstageService
@@ -52,7 +60,14 @@ type BackstageModel struct {
 	LocalDbService     *DbService
 	LocalDbSecret      *DbSecret
 
-	route *BackstageRoute
+	route     *BackstageRoute
+	ingress   *BackstageIngress
+	httpRoute *BackstageHTTPRoute
+
+	inlineAppConfig *InlineAppConfig
+	dynamicPlugins  *DynamicPlugins
+
+	dynamicPluginsCache *DynamicPluginsCache
 
 	RuntimeObjects []RuntimeObject
 
@@ -105,7 +120,7 @@ func registerConfig(key string, factory ObjectFactory) {
 }
 
 // InitObjects performs a main loop for configuring and making the array of objects to reconcile
-func InitObjects(ctx context.Context, backstage bsv1.Backstage, externalConfig ExternalConfig, ownsRuntime bool, isOpenshift bool, scheme *runtime.Scheme) (*BackstageModel, error) {
+func InitObjects(ctx context.Context, backstage bsv1.Backstage, externalConfig ExternalConfig, ownsRuntime bool, platform Platform, scheme *runtime.Scheme) (*BackstageModel, error) {
 
 	// 3 phases of Backstage configuration:
 	// 1- load from Operator defaults, modify metadata (labels, selectors..) and namespace as needed
@@ -116,7 +131,7 @@ func InitObjects(ctx context.Context, backstage bsv1.Backstage, externalConfig E
 	lg := log.FromContext(ctx)
 	lg.V(1)
 
-	model := &BackstageModel{RuntimeObjects: make([]RuntimeObject, 0), ExternalConfig: externalConfig, localDbEnabled: backstage.Spec.IsLocalDbEnabled(), isOpenshift: isOpenshift}
+	model := &BackstageModel{RuntimeObjects: make([]RuntimeObject, 0), ExternalConfig: externalConfig, localDbEnabled: backstage.Spec.IsLocalDbEnabled(), platform: platform}
 
 	// looping through the registered runtimeConfig objects initializing the model
 	for _, conf := range runtimeConfig {
@@ -152,6 +167,15 @@ func InitObjects(ctx context.Context, backstage bsv1.Backstage, externalConfig E
 		}
 	}
 
+	// copies of the objects referred from other namespaces
+	for _, obj := range externalConfig.Mirrors {
+		mirror := &MirroredObject{obj: obj}
+		if _, err := mirror.addToModel(model, backstage); err != nil {
+			return nil, fmt.Errorf("failed to initialize backstage, reason: %s", err)
+		}
+		setMetaInfo(mirror, backstage, ownsRuntime, scheme)
+	}
+
 	// set generic metainfo and validate all
 	for _, v := range model.RuntimeObjects {
 		err := v.validate(model, backstage)
@@ -160,6 +184,15 @@ func InitObjects(ctx context.Context, backstage bsv1.Backstage, externalConfig E
 		}
 	}
 
+	// the entries of the dynamic plugins cache in use, known once the Deployment is validated
+	if model.dynamicPluginsCache != nil && model.dynamicPluginsCache.keys != nil {
+		keys := model.dynamicPluginsCache.keys
+		if _, err := keys.addToModel(model, backstage); err != nil {
+			return nil, fmt.Errorf("failed to initialize backstage, reason: %s", err)
+		}
+		setMetaInfo(keys, backstage, ownsRuntime, scheme)
+	}
+
 	// sort for reconciliation number optimization
 	model.sortRuntimeObjects()
 
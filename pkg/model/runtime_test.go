@@ -56,7 +56,7 @@ func TestInitDefaultDeploy(t *testing.T) {
 
 	testObj := createBackstageTest(bs).withDefaultConfig(true)
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 
 	assert.NoError(t, err)
 	assert.True(t, len(model.RuntimeObjects) > 0)
@@ -94,7 +94,7 @@ func TestIfEmptyObjectIsValid(t *testing.T) {
 
 	assert.False(t, bs.Spec.IsLocalDbEnabled())
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 2, len(model.RuntimeObjects))
@@ -116,7 +116,7 @@ func TestAddToModel(t *testing.T) {
 	}
 	testObj := createBackstageTest(bs).withDefaultConfig(true)
 
-	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, false, testObj.scheme)
+	model, err := InitObjects(context.TODO(), bs, testObj.externalConfig, true, Platform{}, testObj.scheme)
 	assert.NoError(t, err)
 	assert.NotNil(t, model)
 	assert.NotNil(t, model.RuntimeObjects)
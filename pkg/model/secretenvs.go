@@ -44,7 +44,7 @@ func (p *SecretEnvs) Object() client.Object {
 	return p.Secret
 }
 
-func addSecretEnvs(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment) error {
+func addSecretEnvs(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment, model *BackstageModel) error {
 
 	if spec.Application == nil || spec.Application.ExtraEnvs == nil || spec.Application.ExtraEnvs.Secrets == nil {
 		return nil
@@ -52,7 +52,7 @@ func addSecretEnvs(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment) e
 
 	for _, sec := range spec.Application.ExtraEnvs.Secrets {
 		se := SecretEnvs{
-			Secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: sec.Name}},
+			Secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName(model.ExternalConfig.ExtraEnvSecrets, sec)}},
 			Key:    sec.Key,
 		}
 		se.updatePod(deployment)
@@ -96,3 +96,12 @@ func (p *SecretEnvs) updatePod(deployment *appsv1.Deployment) {
 	utils.AddEnvVarsFrom(&deployment.Spec.Template.Spec.Containers[0], utils.SecretObjectKind,
 		p.Secret.Name, p.Key)
 }
+
+// secretName returns the name of the Secret referred by ref in the Backstage namespace,
+// i.e. the name of the mirror if the Secret is of another namespace
+func secretName(secrets map[string]corev1.Secret, ref v1alpha2.ObjectKeyRef) string {
+	if secret, ok := secrets[ExternalConfigKey(ref)]; ok {
+		return secret.Name
+	}
+	return ref.Name
+}
@@ -15,8 +15,6 @@
 package model
 
 import (
-	"fmt"
-
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -43,7 +41,7 @@ func init() {
 	registerConfig("secret-files.yaml", SecretFilesFactory{})
 }
 
-func addSecretFiles(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment) error {
+func addSecretFiles(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment, model *BackstageModel) error {
 
 	if spec.Application == nil || spec.Application.ExtraFiles == nil || spec.Application.ExtraFiles.Secrets == nil {
 		return nil
@@ -53,13 +51,14 @@ func addSecretFiles(spec v1alpha2.BackstageSpec, deployment *appsv1.Deployment)
 		mp = spec.Application.ExtraFiles.MountPath
 	}
 
+	if errs := validateSecretFiles(spec); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
 	for _, sec := range spec.Application.ExtraFiles.Secrets {
-		if sec.Key == "" {
-			return fmt.Errorf("key is required to mount extra file with secret %s", sec.Name)
-		}
 		sf := SecretFiles{
 			Secret: &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{Name: sec.Name},
+				ObjectMeta: metav1.ObjectMeta{Name: secretName(model.ExternalConfig.ExtraFileSecrets, sec)},
 				// TODO it is not correct, there may not be such a secret key
 				// it is done for 0.1.0 compatibility only
 				StringData: map[string]string{sec.Key: ""},
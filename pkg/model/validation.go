@@ -0,0 +1,144 @@
+//
+// Copyright (c) 2023 Red Hat, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bsv1 "redhat-developer/red-hat-developer-hub-operator/api/v1alpha2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateSpec checks the Backstage spec and the external configuration it refers to
+// with the same checks applied while initializing the model (see InitObjects).
+// Used by the validating webhook to reject invalid Backstage CRs at admission time.
+// The content of the objects missing in externalConfig (not read) is not checked.
+func ValidateSpec(spec bsv1.BackstageSpec, externalConfig ExternalConfig) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateSecretFiles(spec)...)
+	if externalConfig.DynamicPlugins.Name != "" {
+		if err := validateDynamicPluginsConfigMap(spec, &externalConfig.DynamicPlugins); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateRouteTLS(spec); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateInlineAppConfig(spec); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateInlineDynamicPlugins(spec); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateDynamicPluginsRegistry(spec, externalConfig)...)
+	return errs
+}
+
+// every Secret mounted as extra file has to have a key
+func validateSecretFiles(spec bsv1.BackstageSpec) field.ErrorList {
+	if spec.Application == nil || spec.Application.ExtraFiles == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	path := field.NewPath("spec", "application", "extraFiles", "secrets")
+	for i, sec := range spec.Application.ExtraFiles.Secrets {
+		if sec.Key == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("key"),
+				fmt.Sprintf("key is required to mount extra file with secret %s", sec.Name)))
+		}
+	}
+	return errs
+}
+
+// dynamic plugins ConfigMap has to contain the only dynamic-plugins.yaml key with valid plugins configuration
+func validateDynamicPluginsConfigMap(spec bsv1.BackstageSpec, cm *corev1.ConfigMap) *field.Error {
+	if spec.Application == nil || spec.Application.DynamicPluginsConfigMapName == "" {
+		return nil
+	}
+	if cm.Data == nil || len(cm.Data) != 1 || cm.Data[DynamicPluginsFile] == "" {
+		return field.Invalid(field.NewPath("spec", "application", "dynamicPluginsConfigMapName"),
+			spec.Application.DynamicPluginsConfigMapName,
+			fmt.Sprintf("dynamic plugin configMap expects exactly one key named '%s' ", DynamicPluginsFile))
+	}
+	if _, err := parseDynamicPlugins(cm.Data[DynamicPluginsFile]); err != nil {
+		return field.Invalid(field.NewPath("spec", "application", "dynamicPluginsConfigMapName"),
+			spec.Application.DynamicPluginsConfigMapName, err.Error())
+	}
+	return nil
+}
+
+// Route certificate can be set either inline or as a Secret reference, not both
+func validateRouteTLS(spec bsv1.BackstageSpec) *field.Error {
+	if spec.Application == nil || spec.Application.Route == nil || spec.Application.Route.TLS == nil {
+		return nil
+	}
+	tls := spec.Application.Route.TLS
+	if tls.Certificate != "" && tls.ExternalCertificateSecretName != "" {
+		return field.Forbidden(field.NewPath("spec", "application", "route", "tls", "externalCertificateSecretName"),
+			"externalCertificateSecretName is forbidden when certificate is set")
+	}
+	return nil
+}
+
+// inline app-config has to be an object (map of the app-config keys)
+func validateInlineAppConfig(spec bsv1.BackstageSpec) *field.Error {
+	if _, err := NewInlineAppConfigMap(bsv1.Backstage{Spec: spec}); err != nil {
+		return field.Invalid(field.NewPath("spec", "application", "appConfig", "inline"), "", err.Error())
+	}
+	return nil
+}
+
+// pluginConfig of the inline dynamic plugin has to be an object
+func validateInlineDynamicPlugins(spec bsv1.BackstageSpec) *field.Error {
+	if spec.Application == nil {
+		return nil
+	}
+	for i, plugin := range spec.Application.DynamicPlugins {
+		if plugin.PluginConfig == nil {
+			continue
+		}
+		var content map[string]interface{}
+		if err := json.Unmarshal(plugin.PluginConfig.Raw, &content); err != nil {
+			return field.Invalid(field.NewPath("spec", "application", "dynamicPlugins").Index(i).Child("pluginConfig"),
+				"", "pluginConfig has to be an object")
+		}
+	}
+	return nil
+}
+
+// the objects of dynamic plugins registry have to contain the referred (or default) key
+func validateDynamicPluginsRegistry(spec bsv1.BackstageSpec, externalConfig ExternalConfig) field.ErrorList {
+	if spec.Application == nil || spec.Application.DynamicPluginsRegistry == nil {
+		return nil
+	}
+	registry := spec.Application.DynamicPluginsRegistry
+	path := field.NewPath("spec", "application", "dynamicPluginsRegistry")
+	var errs field.ErrorList
+	check := func(ref *bsv1.ObjectKeyRef, defaultKey string, keys map[string]bool, child string) {
+		if ref == nil {
+			return
+		}
+		if _, err := registryObjectKey(*ref, defaultKey, keys); err != nil {
+			errs = append(errs, field.Invalid(path.Child(child, "key"), ref.Key, err.Error()))
+		}
+	}
+	check(registry.Npmrc, DynamicPluginsNpmrcKey, secretKeys(&externalConfig.DynamicPluginsNpmrc), "npmrc")
+	check(registry.CABundle, DynamicPluginsCABundleKey, configMapKeys(&externalConfig.DynamicPluginsCABundle), "caBundle")
+	check(registry.OCIAuth, DynamicPluginsOCIAuthKey, secretKeys(&externalConfig.DynamicPluginsOCIAuth), "ociAuth")
+	return errs
+}
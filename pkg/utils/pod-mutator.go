@@ -64,7 +64,7 @@ func MountFilesFrom(podSpec *corev1.PodSpec, container *corev1.Container, kind O
 	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: volName, VolumeSource: volSrc})
 
 	if data != nil {
-		for file := range data {
+		for _, file := range SortedKeys(data) {
 			if fileName == "" || fileName == file {
 				vm := corev1.VolumeMount{Name: volName, MountPath: filepath.Join(mountPath, file), SubPath: file, ReadOnly: true}
 				container.VolumeMounts = append(container.VolumeMounts, vm)
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"k8s.io/client-go/discovery"
@@ -32,12 +33,18 @@ import (
 
 const maxK8sResourceNameLength = 63
 
+const (
+	KubeNameLabel     = "app.kubernetes.io/name"
+	KubeInstanceLabel = "app.kubernetes.io/instance"
+	KubeNameValue     = "backstage"
+)
+
 func SetKubeLabels(labels map[string]string, backstageName string) map[string]string {
 	if labels == nil {
 		labels = map[string]string{}
 	}
-	labels["app.kubernetes.io/name"] = "backstage"
-	labels["app.kubernetes.io/instance"] = backstageName
+	labels[KubeNameLabel] = KubeNameValue
+	labels[KubeInstanceLabel] = backstageName
 
 	return labels
 }
@@ -93,7 +100,14 @@ func ReadYamlFile(path string, object interface{}) error {
 	return ReadYaml(b, object)
 }
 
+// DefaultConfigDir overrides the directory Operator's default configuration is read from.
+// If empty, $LOCALBIN/default-config is used
+var DefaultConfigDir = ""
+
 func DefFile(key string) string {
+	if DefaultConfigDir != "" {
+		return filepath.Join(DefaultConfigDir, key)
+	}
 	return filepath.Join(os.Getenv("LOCALBIN"), "default-config", key)
 }
 
@@ -157,3 +171,13 @@ func ToRFC1123Label(str string) string {
 
 	return name
 }
+
+// SortedKeys returns the keys of the map sorted, to iterate the map in deterministic order
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}